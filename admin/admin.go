@@ -0,0 +1,202 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/crontab"
+	"github.com/fufuok/pkg/logger"
+	"github.com/fufuok/pkg/master"
+	"github.com/fufuok/pkg/stats"
+)
+
+// GroupName 管理接口的 Web 服务分组名, 在 web_conf.groups 中为该分组配置监听地址后启用
+// 接口需同时通过白名单和签名 (WEB_SIGN_KEY) 校验, 未配置签名密钥时拒绝所有请求
+var GroupName = "admin"
+
+// DebugMaxDuration 临时开启 Debug 日志的最长时间
+var DebugMaxDuration = 24 * time.Hour
+
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrUnknownStats = errors.New("unknown stats")
+)
+
+// Job 定时任务状态
+type Job struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`
+	Running bool      `json:"running"`
+	Paused  bool      `json:"paused"`
+	Prev    time.Time `json:"prev"`
+	Next    time.Time `json:"next"`
+}
+
+var (
+	statsMu    sync.RWMutex
+	statsNames []string
+	statsFuncs = make(map[string]func() any)
+)
+
+// 内置统计项
+func init() {
+	RegisterStats("sys", func() any { return stats.SYSStats() })
+	RegisterStats("main", func() any { return stats.MainStats() })
+	RegisterStats("web", func() any { return stats.WebStats() })
+	RegisterStats("metric", func() any { return stats.MetricStats() })
+	RegisterStats("redis", func() any { return stats.RedisStats() })
+	RegisterStats("crontab", func() any { return crontab.DataStatsJSON() })
+}
+
+// RegisterStats 注册或替换统计项, 供 Stats 输出
+func RegisterStats(name string, fn func() any) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if _, ok := statsFuncs[name]; !ok {
+		statsNames = append(statsNames, name)
+	}
+	statsFuncs[name] = fn
+}
+
+// Stats 输出指定的统计项, 未指定时输出全部
+func Stats(names ...string) (map[string]any, error) {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	if len(names) == 0 {
+		names = statsNames
+	}
+	data := make(map[string]any, len(names))
+	for _, name := range names {
+		fn, ok := statsFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStats, name)
+		}
+		data[name] = fn()
+	}
+	return data, nil
+}
+
+// Reload 立即重新加载配置, 返回 false 表示已有未处理的重载请求
+func Reload() bool {
+	logger.Warn().Msg("Admin: reload")
+	return master.Reload()
+}
+
+// FetchNow 立即获取一次远端配置, 返回触发的获取任务数
+func FetchNow() int {
+	n := master.FetchRemoteNow()
+	logger.Warn().Int("count", n).Msg("Admin: fetch remote config")
+	return n
+}
+
+// Jobs 定时任务列表
+func Jobs() []Job {
+	jobs := crontab.Jobs()
+	list := make([]Job, len(jobs))
+	for i, j := range jobs {
+		list[i] = Job{
+			Name:    j.Name(),
+			Spec:    j.Spec(),
+			Running: j.IsRunning(),
+			Paused:  j.IsPaused(),
+			Prev:    j.Prev(),
+			Next:    j.Next(),
+		}
+	}
+	return list
+}
+
+// StopJob 暂停定时任务, 可通过 StartJob 恢复
+func StopJob(name string) error {
+	j, ok := crontab.GetJob(name)
+	if !ok {
+		return ErrJobNotFound
+	}
+	j.Pause()
+	return nil
+}
+
+// StartJob 恢复暂停的定时任务
+func StartJob(name string) error {
+	j, ok := crontab.GetJob(name)
+	if !ok {
+		return ErrJobNotFound
+	}
+	_, err := j.Resume()
+	return err
+}
+
+// LogLevels 当前日志级别
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// LogLevel 当前全局及各模块的日志级别
+func LogLevel() LogLevels {
+	levels := LogLevels{
+		Level:   common.LogLevel().String(),
+		Modules: make(map[string]string),
+	}
+	for name, lv := range common.LogModuleLevels() {
+		levels.Modules[name] = lv.String()
+	}
+	return levels
+}
+
+// SetLogLevel 运行时调整日志级别: trace, debug, info, warn, error, fatal, panic, disabled
+// 指定模块时仅调整模块的日志级别, 日志配置变化重载后恢复为配置的级别
+func SetLogLevel(level string, modules ...string) error {
+	lv, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil || lv == zerolog.NoLevel {
+		return fmt.Errorf("invalid log level: %q", level)
+	}
+	if len(modules) == 0 {
+		common.SetLogLevel(lv)
+	}
+	for _, name := range modules {
+		common.SetLogModuleLevel(name, lv)
+	}
+	logger.Warn().Str("level", lv.String()).Strs("modules", modules).Msg("Admin: log level changed")
+	return nil
+}
+
+// DebugFor 临时开启 Debug 日志, 到期后自动恢复, 最长 DebugMaxDuration
+// 指定模块时仅调整模块的日志级别
+func DebugFor(d time.Duration, modules ...string) error {
+	if d <= 0 || d > DebugMaxDuration {
+		return fmt.Errorf("invalid debug duration: %s (max %s)", d, DebugMaxDuration)
+	}
+	common.DebugLogFor(d, modules...)
+	logger.Warn().Dur("duration", d).Strs("modules", modules).Msg("Admin: debug log enabled")
+	return nil
+}
+
+// AlarmOn 是否发出报警消息
+func AlarmOn() bool {
+	return common.IsAlarmOn()
+}
+
+// SetAlarm 开启或关闭报警消息, 关闭时仅记录日志
+func SetAlarm(on bool) {
+	if on {
+		common.SetAlarmOn()
+	} else {
+		common.SetAlarmOff()
+	}
+	logger.Warn().Bool("alarm_on", on).Msg("Admin: alarm switch changed")
+}
+
+// StatsNames 已注册的统计项名称
+func StatsNames() []string {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	return slices.Clone(statsNames)
+}
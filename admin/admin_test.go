@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/rs/zerolog"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/crontab"
+)
+
+func TestMain(m *testing.M) {
+	config.InitTester()
+	common.InitTester()
+	crontab.InitTester()
+
+	exitCode := m.Run()
+
+	crontab.StopTester()
+	common.StopTester()
+	config.StopTester()
+
+	os.Exit(exitCode)
+}
+
+type nopRunner struct{}
+
+func (nopRunner) Run(context.Context) error { return nil }
+
+func TestJobs(t *testing.T) {
+	_, err := crontab.AddJob(context.Background(), "admin_test", "@every 1h", nopRunner{})
+	assert.Nil(t, err)
+	defer crontab.StopJob("admin_test")
+
+	assert.Nil(t, StopJob("admin_test"))
+	i := slices.IndexFunc(Jobs(), func(j Job) bool { return j.Name == "admin_test" })
+	assert.True(t, i >= 0)
+	assert.True(t, Jobs()[i].Paused)
+	assert.Equal(t, "@every 1h", Jobs()[i].Spec)
+
+	assert.Nil(t, StartJob("admin_test"))
+	j, _ := crontab.GetJob("admin_test")
+	assert.True(t, j.IsRunning())
+
+	assert.True(t, errors.Is(StopJob("none"), ErrJobNotFound))
+	assert.True(t, errors.Is(StartJob("none"), ErrJobNotFound))
+}
+
+func TestSetLogLevel(t *testing.T) {
+	old := common.LogLevel()
+	defer common.SetLogLevel(old)
+
+	assert.Nil(t, SetLogLevel("WARN"))
+	assert.Equal(t, "warn", LogLevel().Level)
+	assert.Equal(t, zerolog.WarnLevel, common.LogSampled().GetLevel())
+	assert.Equal(t, zerolog.WarnLevel, common.LogAlarm().GetLevel())
+	assert.NotNil(t, SetLogLevel("verbose"))
+	assert.NotNil(t, SetLogLevel(""))
+
+	assert.Nil(t, SetLogLevel("error", "admin_test"))
+	assert.Equal(t, "error", LogLevel().Modules["admin_test"])
+	assert.Equal(t, "warn", LogLevel().Level)
+
+	assert.NotNil(t, DebugFor(0))
+	assert.NotNil(t, DebugFor(DebugMaxDuration+time.Second))
+}
+
+func TestDebugFor(t *testing.T) {
+	old := common.LogLevel()
+	defer common.SetLogLevel(old)
+	common.SetLogLevel(zerolog.WarnLevel)
+
+	assert.Nil(t, DebugFor(50*time.Millisecond, "debug_test"))
+	assert.Equal(t, zerolog.DebugLevel, common.LogModule("debug_test").GetLevel())
+	assert.Equal(t, zerolog.WarnLevel, common.LogLevel())
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, zerolog.WarnLevel, common.LogModule("debug_test").GetLevel())
+
+	// 到期前再次开启时重新计时, 到期后恢复为首次开启前的级别
+	assert.Nil(t, DebugFor(50*time.Millisecond))
+	assert.Nil(t, DebugFor(150*time.Millisecond))
+	assert.Equal(t, zerolog.DebugLevel, common.LogLevel())
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, zerolog.DebugLevel, common.LogLevel())
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, zerolog.WarnLevel, common.LogLevel())
+	assert.Equal(t, zerolog.WarnLevel, common.LogModule("debug_test").GetLevel())
+
+	// 期间运行时调整的级别在到期后保留
+	assert.Nil(t, DebugFor(50*time.Millisecond))
+	assert.Nil(t, SetLogLevel("error"))
+	assert.Nil(t, SetLogLevel("info", "debug_test"))
+	assert.Equal(t, zerolog.DebugLevel, common.LogLevel())
+	assert.Equal(t, zerolog.InfoLevel, common.LogModule("debug_test").GetLevel())
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, zerolog.ErrorLevel, common.LogLevel())
+	assert.Equal(t, zerolog.InfoLevel, common.LogModule("debug_test").GetLevel())
+}
+
+func TestSetAlarm(t *testing.T) {
+	defer common.SetAlarmOn()
+	SetAlarm(false)
+	assert.False(t, AlarmOn())
+}
+
+func TestStats(t *testing.T) {
+	RegisterStats("test", func() any { return 1 })
+	assert.True(t, slices.Contains(StatsNames(), "test"))
+
+	data, err := Stats("test", "crontab")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(data))
+	assert.Equal(t, 1, data["test"])
+
+	_, err = Stats("none")
+	assert.True(t, errors.Is(err, ErrUnknownStats))
+}
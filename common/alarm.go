@@ -45,6 +45,11 @@ func SetAlarmOff() {
 	logAlarmWriter.off.Store(true)
 }
 
+// IsAlarmOn 是否发出报警消息 (配置开启且未被 SetAlarmOff 关闭)
+func IsAlarmOn() bool {
+	return logAlarmOnConf && !logAlarmWriter.off.Load()
+}
+
 // SendAlarm 发送自定义报警消息
 func SendAlarm(code, info, more string) {
 	cfg := config.Config().LogConf
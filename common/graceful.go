@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// 平滑重启时, 旧进程将登记的监听器按顺序作为文件描述符 3, 4, ... 传递给新进程,
+// 新进程通过 Listen 按地址取回, 全部取回后通知旧进程就绪, 旧进程关闭服务并处理完已有请求
+var (
+	gracefulMu         sync.Mutex
+	gracefulListeners  []gracefulListener
+	inheritedListeners map[string]net.Listener
+	inheritedOnce      sync.Once
+	inheritedReadyFd   int
+	shutdownFuncs      []func(ctx context.Context) error
+)
+
+type gracefulListener struct {
+	addr string
+	ln   net.Listener
+}
+
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+// Listen 创建 TCP 监听, 平滑重启的新进程优先使用旧进程传递的同地址监听器
+// 监听器被登记, 下次平滑重启时传递给新进程
+func Listen(addr string) (net.Listener, error) {
+	loadInheritedListeners()
+	gracefulMu.Lock()
+	defer gracefulMu.Unlock()
+	ln, ok := inheritedListeners[addr]
+	if ok {
+		delete(inheritedListeners, addr)
+	} else {
+		var err error
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	gracefulListeners = append(gracefulListeners, gracefulListener{addr: addr, ln: ln})
+	return ln, nil
+}
+
+// ListenerFiles 已登记监听器的地址和文件 (复制的文件描述符), 用于传递给新进程, 使用后需关闭文件
+func ListenerFiles() ([]string, []*os.File, error) {
+	gracefulMu.Lock()
+	defer gracefulMu.Unlock()
+	addrs := make([]string, 0, len(gracefulListeners))
+	files := make([]*os.File, 0, len(gracefulListeners))
+	for _, gl := range gracefulListeners {
+		fl, ok := gl.ln.(fileListener)
+		if !ok {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("listener does not support file: %s", gl.addr)
+		}
+		f, err := fl.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("listener file %s: %w", gl.addr, err)
+		}
+		addrs = append(addrs, gl.addr)
+		files = append(files, f)
+	}
+	return addrs, files, nil
+}
+
+// GracefulInherited 是否为平滑重启启动的新进程
+func GracefulInherited() bool {
+	loadInheritedListeners()
+	return inheritedReadyFd > 0
+}
+
+// PendingListeners 从旧进程继承但尚未被 Listen 取回的监听地址
+func PendingListeners() []string {
+	loadInheritedListeners()
+	gracefulMu.Lock()
+	defer gracefulMu.Unlock()
+	addrs := make([]string, 0, len(inheritedListeners))
+	for addr := range inheritedListeners {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// NotifyGracefulReady 通知旧进程新进程已就绪, 关闭未取回的继承监听器, 非平滑重启启动时忽略
+func NotifyGracefulReady() error {
+	if !GracefulInherited() {
+		return nil
+	}
+	gracefulMu.Lock()
+	for addr, ln := range inheritedListeners {
+		_ = ln.Close()
+		delete(inheritedListeners, addr)
+	}
+	fd := inheritedReadyFd
+	inheritedReadyFd = 0
+	gracefulMu.Unlock()
+	if fd == 0 {
+		return nil
+	}
+
+	f := os.NewFile(uintptr(fd), "graceful-ready")
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err := f.Write([]byte{1})
+	return err
+}
+
+// RegisterShutdown 登记平滑重启时的服务关闭函数, 如: http.Server.Shutdown
+// 旧进程在新进程就绪后调用, 应停止接受新连接并等待已有请求处理完成
+func RegisterShutdown(fn func(ctx context.Context) error) {
+	gracefulMu.Lock()
+	shutdownFuncs = append(shutdownFuncs, fn)
+	gracefulMu.Unlock()
+}
+
+// Shutdown 并发执行所有登记的服务关闭函数, 返回所有错误
+func Shutdown(ctx context.Context) error {
+	gracefulMu.Lock()
+	fns := shutdownFuncs
+	shutdownFuncs = nil
+	gracefulMu.Unlock()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, fn := range fns {
+		wg.Go(func() {
+			if err := fn(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// 读取旧进程传递的监听器, 读取后清除环境变量, 避免传递给其他子进程
+func loadInheritedListeners() {
+	inheritedOnce.Do(func() {
+		inheritedListeners = make(map[string]net.Listener)
+		fds := os.Getenv(config.GracefulListenFdsEnvName)
+		ready := os.Getenv(config.GracefulReadyFdEnvName)
+		_ = os.Unsetenv(config.GracefulListenFdsEnvName)
+		_ = os.Unsetenv(config.GracefulReadyFdEnvName)
+		if ready == "" {
+			return
+		}
+		if fd, err := strconv.Atoi(ready); err == nil && fd > 2 {
+			inheritedReadyFd = fd
+		}
+		if fds == "" {
+			return
+		}
+		for i, addr := range strings.Split(fds, ",") {
+			f := os.NewFile(uintptr(3+i), addr)
+			ln, err := net.FileListener(f)
+			_ = f.Close()
+			if err != nil {
+				Log().Error().Err(err).Str("addr", addr).Msg("Failed to inherit listener")
+				continue
+			}
+			inheritedListeners[addr] = ln
+		}
+	})
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/config"
+)
+
+func TestGracefulListen(t *testing.T) {
+	assert.False(t, GracefulInherited())
+	assert.Nil(t, NotifyGracefulReady())
+	assert.Equal(t, 0, len(PendingListeners()))
+
+	ln, err := Listen("127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() {
+		_ = ln.Close()
+	}()
+	addrs, files, err := ListenerFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"127.0.0.1:0"}, addrs)
+	assert.Equal(t, 1, len(files))
+
+	// 复制的监听器与原监听器为同一套接字
+	dup, err := net.FileListener(files[0])
+	assert.Nil(t, err)
+	closeFiles(files)
+	assert.Equal(t, ln.Addr().String(), dup.Addr().String())
+	_ = dup.Close()
+}
+
+func TestShutdown(t *testing.T) {
+	n := 0
+	RegisterShutdown(func(ctx context.Context) error {
+		n++
+		return nil
+	})
+	RegisterShutdown(func(ctx context.Context) error {
+		return errors.New("timeout")
+	})
+	err := Shutdown(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Nil(t, Shutdown(context.Background()))
+}
+
+func TestGracefulInherit(t *testing.T) {
+	if os.Getenv("GRACEFUL_TEST_CHILD") == "1" {
+		// 新进程: 取回继承的监听器, 通知就绪后响应一个连接
+		if !GracefulInherited() {
+			os.Exit(2)
+		}
+		ln, err := Listen("127.0.0.1:0")
+		if err != nil || len(PendingListeners()) != 0 || NotifyGracefulReady() != nil {
+			os.Exit(3)
+		}
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(4)
+		}
+		_, _ = conn.Write([]byte("child"))
+		_ = conn.Close()
+		os.Exit(0)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f, err := ln.(*net.TCPListener).File()
+	assert.Nil(t, err)
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestGracefulInherit$")
+	cmd.ExtraFiles = []*os.File{f, w}
+	cmd.Env = append(os.Environ(), "GRACEFUL_TEST_CHILD=1",
+		config.GracefulListenFdsEnvName+"=127.0.0.1:0",
+		config.GracefulReadyFdEnvName+"="+strconv.Itoa(4),
+	)
+	assert.Nil(t, cmd.Start())
+	_ = w.Close()
+	_ = f.Close()
+
+	_ = r.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, err = r.Read(make([]byte, 1))
+	assert.Nil(t, err)
+	_ = r.Close()
+
+	// 旧进程关闭监听器, 连接由新进程处理
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	b, _ := io.ReadAll(conn)
+	_ = conn.Close()
+	assert.Equal(t, "child", string(b))
+	assert.Nil(t, cmd.Wait())
+}
@@ -1,9 +1,16 @@
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
+	"github.com/fufuok/cache/xsync"
 	"github.com/fufuok/utils/xhash"
 )
 
@@ -66,3 +73,71 @@ func VerifySignTTL(key, sign string, second int64) bool {
 	now := GTimestamp()
 	return ts >= now-second && ts <= now+second
 }
+
+// GenRequestSign 生成绑定请求的签名, 签名不能用于其他接口或其他参数
+// 算法: md5(ts\nMETHOD\npath\n规范化查询参数\nsha256(body)\nkey), 查询参数按名称和值排序
+// 结果: ts+sign
+func GenRequestSign(ts int64, key, method, path, rawQuery string, body []byte) string {
+	return genRequestSignString(strconv.FormatInt(ts, 10), key, method, path, rawQuery, body)
+}
+
+// GenRequestSignNow 以当前时间时间戳生成绑定请求的签名
+func GenRequestSignNow(key, method, path, rawQuery string, body []byte) (int64, string) {
+	ts := GTimestamp()
+	return ts, GenRequestSign(ts, key, method, path, rawQuery, body)
+}
+
+// VerifyRequestSignTTL 校验绑定请求的签名及签名有效期(当前时间 **秒 范围内有效)
+// 有效期内同一签名仅能使用一次, 重放的请求校验不通过, 同一秒内需发送相同请求时可添加 nonce 等查询参数区分
+func VerifyRequestSignTTL(key, sign, method, path, rawQuery string, body []byte, second int64) bool {
+	if key == "" || len(sign) != 42 || sign != genRequestSignString(sign[:10], key, method, path, rawQuery, body) {
+		return false
+	}
+	ts, _ := strconv.ParseInt(sign[:10], 10, 64)
+	now := GTimestamp()
+	if ts < now-second || ts > now+second {
+		return false
+	}
+	return useRequestSign(sign, ts+second, now)
+}
+
+// 有效期内已使用的签名及其过期时间
+var (
+	usedRequestSigns     = xsync.NewMap[string, int64]()
+	usedRequestSignsScan atomic.Int64
+)
+
+// 记录已使用的签名, 已使用过时返回 false, 每秒最多清理一次过期的签名
+func useRequestSign(sign string, expireAt, now int64) bool {
+	if last := usedRequestSignsScan.Load(); now > last && usedRequestSignsScan.CompareAndSwap(last, now) {
+		usedRequestSigns.Range(func(k string, v int64) bool {
+			if v < now {
+				usedRequestSigns.Delete(k)
+			}
+			return true
+		})
+	}
+	_, loaded := usedRequestSigns.LoadOrStore(sign, expireAt)
+	return !loaded
+}
+
+func genRequestSignString(ts, key, method, path, rawQuery string, body []byte) string {
+	if len(ts) != 10 || key == "" {
+		return ""
+	}
+	bodySum := sha256.Sum256(body)
+	s := strings.Join([]string{ts, strings.ToUpper(method), path, canonicalQuery(rawQuery), hex.EncodeToString(bodySum[:]), key}, "\n")
+	return ts + xhash.MD5Hex(s)
+}
+
+// 规范化查询参数: 按名称和值排序后编码, 无法解析时使用原值
+func canonicalQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	for _, vs := range values {
+		slices.Sort(vs)
+	}
+	return values.Encode()
+}
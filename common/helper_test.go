@@ -15,3 +15,18 @@ func TestGenSign(t *testing.T) {
 	t.Log("sign:", sign)
 	assert.True(t, VerifySignTTL(key, sign, 1))
 }
+
+func TestGenRequestSign(t *testing.T) {
+	key := utils.RandString(18)
+	body := []byte(`{"a":1}`)
+	_, sign := GenRequestSignNow(key, "post", "/admin/config/history/revert", "id=1&file=a.json", body)
+	assert.False(t, VerifyRequestSignTTL(key, sign, "POST", "/admin/reload", "file=a.json&id=1", body, 1))
+	assert.False(t, VerifyRequestSignTTL(key, sign, "GET", "/admin/config/history/revert", "file=a.json&id=1", body, 1))
+	assert.False(t, VerifyRequestSignTTL(key, sign, "POST", "/admin/config/history/revert", "file=b.json&id=1", body, 1))
+	assert.False(t, VerifyRequestSignTTL(key, sign, "POST", "/admin/config/history/revert", "file=a.json&id=1", nil, 1))
+	assert.False(t, VerifySignTTL(key, sign, 1))
+
+	// 查询参数顺序无关, 同一签名仅能使用一次
+	assert.True(t, VerifyRequestSignTTL(key, sign, "POST", "/admin/config/history/revert", "file=a.json&id=1", body, 1))
+	assert.False(t, VerifyRequestSignTTL(key, sign, "POST", "/admin/config/history/revert", "file=a.json&id=1", body, 1))
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -117,15 +118,16 @@ func loadLogger() error {
 			l.Warn().Bool("alarm_on", logAlarmOnConf).Msg("Alarm switch changed")
 		}
 	}
+	// 与当前生效的日志配置相同时无需重建日志记录器 (含 map/slice, 按值比较)
 	cfg := config.Config().LogConf
-	if logCurrentConf == cfg {
+	if logger.Load() != nil && reflect.DeepEqual(logCurrentConf, cfg) {
 		return nil
 	}
-	logCurrentConf = cfg
 
 	if err := newLogger(); err != nil {
 		return err
 	}
+	logCurrentConf = cfg
 
 	// 抽样的日志记录器
 	sampler := &zerolog.BurstSampler{
@@ -140,6 +142,7 @@ func loadLogger() error {
 		ErrorSampler: sampler,
 	}).With().Bool("sampling", true).Logger()
 	logSampled.Store(&newLogSampled)
+	resetLogLevels()
 
 	Log().Warn().Str("version", config.Version).Str("tz", config.DefaultTimeZone).
 		Str("app_name", config.AppName).Str("bin_name", config.BinName).Str("deb_name", config.DebName).
@@ -151,13 +154,24 @@ func loadLogger() error {
 
 // 日志配置
 // 1. Debug 调试时, 日志高亮输出到控制台
-// 2. 非调试模式时, 日志输出到文件(可选关闭高亮, 可选 JSON 输出, 保存最近 10 个 30 天内的日志)
+// 2. 配置了日志输出列表 (log_conf.outputs) 时, 同时写入各输出
+// 3. 否则日志输出到文件(可选关闭高亮, 可选 JSON 输出, 保存最近 10 个 30 天内的日志)
 func newLogger() (err error) {
-	var wr io.Writer
+	var (
+		wr      io.Writer
+		closers []io.Closer
+	)
 	cfg := config.Config().LogConf
 	wr = zerolog.ConsoleWriter{Out: os.Stdout, NoColor: cfg.NoColor, TimeFormat: LogTimeFormat}
-	if !config.Debug {
-		wr, err = lumberjack.NewRoller(
+	switch {
+	case config.Debug:
+	case len(cfg.Outputs) > 0:
+		wr, closers, err = newLogOutputs(cfg)
+		if err != nil {
+			return err
+		}
+	default:
+		roller, err := lumberjack.NewRoller(
 			cfg.File,
 			// 以 MiB 为单位
 			cfg.MaxSize*Megabyte,
@@ -171,6 +185,7 @@ func newLogger() (err error) {
 		if err != nil {
 			return err
 		}
+		wr, closers = roller, []io.Closer{roller}
 		if !cfg.NoPretty {
 			wr = zerolog.ConsoleWriter{Out: wr, NoColor: cfg.NoColor, TimeFormat: LogTimeFormat}
 		}
@@ -184,6 +199,7 @@ func newLogger() (err error) {
 	newLogAlarm := zerolog.New(mw).With().Timestamp().Caller().Logger()
 	newLogAlarm = newLogAlarm.Level(zerolog.Level(cfg.Level))
 	logAlarm.Store(&newLogAlarm)
+	replaceLogOutputs(closers)
 	return nil
 }
 
@@ -0,0 +1,207 @@
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fufuok/cache/xsync"
+	"github.com/rs/zerolog"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// LogModuleFieldName 模块日志记录器的模块名字段
+const LogModuleFieldName = "module"
+
+var (
+	// 运行时调整的日志级别, 日志配置 (log_conf) 变化重载后清除
+	logLevelMu      sync.Mutex
+	logLevel        *zerolog.Level
+	logModuleLevels = make(map[string]zerolog.Level)
+	// 临时开启的 Debug 日志 (DebugLogFor), 与运行时调整的级别分开记录, 到期时仅移除这部分
+	logDebugTimer   *time.Timer
+	logDebugGlobal  bool
+	logDebugModules = make(map[string]struct{})
+
+	// 模块日志记录器
+	logModules = xsync.NewMap[string, *atomic.Pointer[zerolog.Logger]]()
+)
+
+// LogModule 返回模块日志记录器, 带有模块名字段, 日志级别优先使用模块级别
+// 级别优先顺序: 运行时模块级别, 配置模块级别 (log_conf.modules), 运行时全局级别, 配置全局级别 (log_conf.level)
+// common.LogModule("crontab").Debug().Msg("debug")
+func LogModule(name string) *zerolog.Logger {
+	p, ok := logModules.Load(name)
+	if !ok {
+		p = newLogModule(name)
+	}
+	if l := p.Load(); l != nil {
+		return l
+	}
+	return Log()
+}
+
+func newLogModule(name string) *atomic.Pointer[zerolog.Logger] {
+	p, loaded := logModules.LoadOrCompute(name, func() (*atomic.Pointer[zerolog.Logger], bool) {
+		return new(atomic.Pointer[zerolog.Logger]), false
+	})
+	if !loaded {
+		logLevelMu.Lock()
+		storeModuleLogger(name, p)
+		logLevelMu.Unlock()
+	}
+	return p
+}
+
+// LogLevel 当前通用日志级别
+func LogLevel() zerolog.Level {
+	return Log().GetLevel()
+}
+
+// LogModuleLevels 已使用或已配置的模块的当前日志级别
+func LogModuleLevels() map[string]zerolog.Level {
+	levels := make(map[string]zerolog.Level)
+	logModules.Range(func(name string, _ *atomic.Pointer[zerolog.Logger]) bool {
+		levels[name] = LogModule(name).GetLevel()
+		return true
+	})
+	if cfg := config.Config(); cfg != nil {
+		for name := range cfg.LogConf.Modules {
+			levels[name] = LogModule(name).GetLevel()
+		}
+	}
+	return levels
+}
+
+// SetLogLevel 运行时调整通用日志, 抽样日志和报警日志的级别, 未单独设置级别的模块同时生效
+// 日志配置 (log_conf) 变化重载后恢复为配置的级别
+func SetLogLevel(level zerolog.Level) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+	logLevel = &level
+	applyLogLevels()
+}
+
+// SetLogModuleLevel 运行时调整模块日志级别
+// 日志配置 (log_conf) 变化重载后恢复为配置的级别
+func SetLogModuleLevel(name string, level zerolog.Level) {
+	LogModule(name)
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+	logModuleLevels[name] = level
+	applyLogLevels()
+}
+
+// DebugLogFor 临时开启 Debug 日志, 到期后移除临时开启的 Debug 级别, 期间运行时调整的级别保留
+// 未指定模块时调整全局日志级别, 到期前再次调用时重新计时
+func DebugLogFor(d time.Duration, modules ...string) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+	if len(modules) == 0 {
+		logDebugGlobal = true
+	}
+	for _, name := range modules {
+		logDebugModules[name] = struct{}{}
+		logModules.LoadOrStore(name, new(atomic.Pointer[zerolog.Logger]))
+	}
+	applyLogLevels()
+
+	if logDebugTimer != nil {
+		logDebugTimer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		logLevelMu.Lock()
+		defer logLevelMu.Unlock()
+		if logDebugTimer != timer {
+			return
+		}
+		clearLogDebug()
+		applyLogLevels()
+		Log().Warn().Msg("Debug log expired, log level restored")
+	})
+	logDebugTimer = timer
+}
+
+// 清除临时开启的 Debug 日志, 调用方持有 logLevelMu
+func clearLogDebug() {
+	if logDebugTimer != nil {
+		logDebugTimer.Stop()
+		logDebugTimer = nil
+	}
+	logDebugGlobal = false
+	clear(logDebugModules)
+}
+
+// 日志配置重载后, 清除运行时调整的级别
+func resetLogLevels() {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+	logLevel = nil
+	clear(logModuleLevels)
+	clearLogDebug()
+	applyLogLevels()
+}
+
+// 按当前配置和运行时级别更新所有日志记录器, 调用方持有 logLevelMu
+func applyLogLevels() {
+	level := globalLogLevel()
+	for _, p := range []*atomic.Pointer[zerolog.Logger]{&logger, &logSampled, &logAlarm} {
+		if l := p.Load(); l != nil && l.GetLevel() != level {
+			newLog := l.Level(level)
+			p.Store(&newLog)
+		}
+	}
+	logModules.Range(func(name string, p *atomic.Pointer[zerolog.Logger]) bool {
+		storeModuleLogger(name, p)
+		return true
+	})
+}
+
+func storeModuleLogger(name string, p *atomic.Pointer[zerolog.Logger]) {
+	l := logger.Load()
+	if l == nil {
+		return
+	}
+	newLog := l.With().Str(LogModuleFieldName, name).Logger().Level(moduleLogLevel(name))
+	p.Store(&newLog)
+}
+
+// 全局日志级别, 临时开启 Debug 日志期间最高为 Debug 级别
+func globalLogLevel() zerolog.Level {
+	level := baseLogLevel()
+	if logDebugGlobal {
+		level = min(level, zerolog.DebugLevel)
+	}
+	return level
+}
+
+func baseLogLevel() zerolog.Level {
+	if logLevel != nil {
+		return *logLevel
+	}
+	if cfg := config.Config(); cfg != nil {
+		return zerolog.Level(cfg.LogConf.Level)
+	}
+	if l := logger.Load(); l != nil {
+		return l.GetLevel()
+	}
+	return zerolog.GlobalLevel()
+}
+
+// 模块日志级别, 临时开启该模块 Debug 日志期间最高为 Debug 级别
+func moduleLogLevel(name string) zerolog.Level {
+	level := globalLogLevel()
+	if l, ok := logModuleLevels[name]; ok {
+		level = l
+	} else if cfg := config.Config(); cfg != nil {
+		if l, ok := cfg.LogConf.Modules[name]; ok {
+			level = zerolog.Level(l)
+		}
+	}
+	if _, ok := logDebugModules[name]; ok {
+		level = min(level, zerolog.DebugLevel)
+	}
+	return level
+}
@@ -0,0 +1,332 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/natefinch/lumberjack/v3"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/diode"
+
+	"github.com/fufuok/pkg/config"
+)
+
+var (
+	// LogOutputDialTimeout 网络日志输出 (syslog, tcp, udp) 的连接超时时间
+	LogOutputDialTimeout = 3 * time.Second
+
+	// LogOutputRetryInterval 网络日志输出连接失败后, 该时间内丢弃日志, 不再重试连接
+	LogOutputRetryInterval = 5 * time.Second
+
+	// LogOutputWriteTimeout 网络日志输出的单次写入超时时间, 超时后断开连接
+	LogOutputWriteTimeout = 3 * time.Second
+
+	// LogOutputBufferSize 网络日志输出的缓冲日志条数, 日志在后台写入, 缓冲满时丢弃最早的日志, 不阻塞业务
+	LogOutputBufferSize = 1000
+
+	// LogOutputCloseDelay 重建日志记录器后, 延迟关闭旧的日志输出, 等待写入中的日志完成
+	LogOutputCloseDelay = time.Second
+
+	// 当前日志输出中需关闭的文件和连接
+	logOutputsMu      sync.Mutex
+	logOutputsClosers []io.Closer
+)
+
+// 按 log_conf.outputs 创建日志输出, 各输出按自身的最低级别过滤
+func newLogOutputs(cfg config.LogConf) (zerolog.LevelWriter, []io.Closer, error) {
+	writers := make([]io.Writer, 0, len(cfg.Outputs))
+	closers := make([]io.Closer, 0, len(cfg.Outputs))
+	for i, out := range cfg.Outputs {
+		w, c, err := newLogOutput(out)
+		if err != nil {
+			closeLogOutputs(closers)
+			return nil, nil, fmt.Errorf("log_conf.outputs.%d: %w", i, err)
+		}
+		writers = append(writers, w)
+		if c != nil {
+			closers = append(closers, c)
+		}
+	}
+	return zerolog.MultiLevelWriter(writers...), closers, nil
+}
+
+func newLogOutput(out config.LogOutput) (zerolog.LevelWriter, io.Closer, error) {
+	var (
+		w zerolog.LevelWriter
+		c io.Closer
+	)
+	switch out.Type {
+	case "file":
+		roller, err := lumberjack.NewRoller(
+			out.Address,
+			out.MaxSize*Megabyte,
+			&lumberjack.Options{
+				MaxAge:     time.Duration(out.MaxAge) * Days,
+				MaxBackups: out.MaxBackups,
+				LocalTime:  true,
+				Compress:   true,
+			})
+		if err != nil {
+			return nil, nil, err
+		}
+		w, c = zerolog.LevelWriterAdapter{Writer: roller}, roller
+	case "stdout":
+		w = zerolog.LevelWriterAdapter{Writer: os.Stdout}
+	case "stderr":
+		w = zerolog.LevelWriterAdapter{Writer: os.Stderr}
+	case "syslog":
+		nw := newNetLogWriter([]string{"unixgram", "unix"}, out.Address, syslogHeader(out.Tag))
+		w, c = nw, nw
+	case "tcp", "udp":
+		if out.Address == "" {
+			return nil, nil, errors.New("address is required for " + out.Type + " output")
+		}
+		nw := newNetLogWriter([]string{out.Type}, out.Address, nil)
+		w, c = nw, nw
+	default:
+		return nil, nil, fmt.Errorf("unknown log output type: %q", out.Type)
+	}
+
+	lw := &logOutputWriter{out: w, min: zerolog.TraceLevel}
+	if out.Level != nil {
+		lw.min = zerolog.Level(*out.Level)
+	}
+	switch out.Format {
+	case "", "json":
+	case "console":
+		// 网络输出不使用颜色
+		noColor := out.NoColor || (out.Type != "stdout" && out.Type != "stderr" && out.Type != "file")
+		lw.format = &zerolog.ConsoleWriter{NoColor: noColor, TimeFormat: LogTimeFormat}
+	case "logfmt":
+		lw.format = newLogfmtFormatter()
+	default:
+		return nil, nil, fmt.Errorf("unknown log format: %q", out.Format)
+	}
+	return lw, c, nil
+}
+
+// 替换当前日志输出, 延迟关闭旧的日志输出
+func replaceLogOutputs(closers []io.Closer) {
+	logOutputsMu.Lock()
+	old := logOutputsClosers
+	logOutputsClosers = closers
+	logOutputsMu.Unlock()
+	if len(old) > 0 {
+		time.AfterFunc(LogOutputCloseDelay, func() {
+			closeLogOutputs(old)
+		})
+	}
+}
+
+func closeLogOutputs(closers []io.Closer) {
+	for _, c := range closers {
+		_ = c.Close()
+	}
+}
+
+// 单个日志输出: 按最低级别过滤, 转换日志格式
+type logOutputWriter struct {
+	out    zerolog.LevelWriter
+	min    zerolog.Level
+	format *zerolog.ConsoleWriter
+}
+
+func (w *logOutputWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *logOutputWriter) WriteLevel(l zerolog.Level, p []byte) (int, error) {
+	if l < w.min {
+		return len(p), nil
+	}
+	b := p
+	if w.format != nil {
+		var buf bytes.Buffer
+		f := *w.format
+		f.Out = &buf
+		if _, err := f.Write(p); err != nil {
+			return 0, err
+		}
+		b = buf.Bytes()
+	}
+	if _, err := w.out.WriteLevel(l, b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// logfmt 格式: T=0102 15:04:05 L=info F=caller M="msg" key=value
+func newLogfmtFormatter() *zerolog.ConsoleWriter {
+	part := func(name string) zerolog.Formatter {
+		return func(i any) string {
+			if i == nil {
+				return ""
+			}
+			return name + "=" + logfmtValue(fmt.Sprint(i))
+		}
+	}
+	fieldName := func(i any) string {
+		return fmt.Sprint(i) + "="
+	}
+	fieldValue := func(i any) string {
+		var s string
+		switch v := i.(type) {
+		case []byte:
+			s = string(v)
+		default:
+			s = fmt.Sprint(v)
+		}
+		if strings.HasPrefix(s, `"`) {
+			// 已由 ConsoleWriter 转义
+			return s
+		}
+		return logfmtValue(s)
+	}
+	return &zerolog.ConsoleWriter{
+		NoColor: true,
+		PartsOrder: []string{
+			zerolog.TimestampFieldName,
+			zerolog.LevelFieldName,
+			zerolog.CallerFieldName,
+			zerolog.MessageFieldName,
+		},
+		FormatTimestamp:     part(zerolog.TimestampFieldName),
+		FormatLevel:         part(zerolog.LevelFieldName),
+		FormatCaller:        part(zerolog.CallerFieldName),
+		FormatMessage:       part(zerolog.MessageFieldName),
+		FormatFieldName:     fieldName,
+		FormatFieldValue:    fieldValue,
+		FormatErrFieldName:  fieldName,
+		FormatErrFieldValue: fieldValue,
+	}
+}
+
+func logfmtValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// 网络日志输出, 日志写入非阻塞缓冲 (diode) 后立即返回, 由后台协程写入网络连接
+type netLogWriter struct {
+	header func(l zerolog.Level) []byte
+	out    diode.Writer
+}
+
+func newNetLogWriter(networks []string, addr string, header func(l zerolog.Level) []byte) *netLogWriter {
+	conn := &netLogConn{networks: networks, addr: addr}
+	return &netLogWriter{
+		header: header,
+		out: diode.NewWriter(conn, LogOutputBufferSize, 0, func(missed int) {
+			_, _ = fmt.Fprintf(os.Stderr, "log output %s dropped %d messages\n", addr, missed)
+		}),
+	}
+}
+
+func (w *netLogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *netLogWriter) WriteLevel(l zerolog.Level, p []byte) (int, error) {
+	b := p
+	if w.header != nil {
+		b = append(w.header(l), p...)
+	}
+	_, _ = w.out.Write(b)
+	return len(p), nil
+}
+
+// Close 停止后台写入并关闭连接, 缓冲中未写入的日志丢弃
+func (w *netLogWriter) Close() error {
+	return w.out.Close()
+}
+
+// 网络日志连接, 首次写入时连接, 写入失败或超时时断开, 下次写入时重新连接
+type netLogConn struct {
+	networks []string
+	addr     string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	retryAt time.Time
+}
+
+func (w *netLogConn) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if time.Now().Before(w.retryAt) {
+			return len(p), nil
+		}
+		if err := w.dial(); err != nil {
+			w.retryAt = time.Now().Add(LogOutputRetryInterval)
+			return 0, err
+		}
+	}
+	_ = w.conn.SetWriteDeadline(time.Now().Add(LogOutputWriteTimeout))
+	if _, err := w.conn.Write(p); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *netLogConn) dial() (err error) {
+	for _, network := range w.networks {
+		var conn net.Conn
+		conn, err = net.DialTimeout(network, w.addr, LogOutputDialTimeout)
+		if err == nil {
+			w.conn = conn
+			return nil
+		}
+	}
+	return err
+}
+
+func (w *netLogConn) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslog 消息头 (RFC 3164, 本机 unix socket), 设施: user
+func syslogHeader(tag string) func(l zerolog.Level) []byte {
+	pid := os.Getpid()
+	return func(l zerolog.Level) []byte {
+		const facilityUser = 1 << 3
+		ts := GTimeNow().Format(time.Stamp)
+		return fmt.Appendf(nil, "<%d>%s %s[%d]: ", facilityUser|syslogSeverity(l), ts, tag, pid)
+	}
+}
+
+func syslogSeverity(l zerolog.Level) int {
+	switch l {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return 7
+	case zerolog.WarnLevel:
+		return 4
+	case zerolog.ErrorLevel:
+		return 3
+	case zerolog.FatalLevel:
+		return 2
+	case zerolog.PanicLevel:
+		return 0
+	default:
+		return 6
+	}
+}
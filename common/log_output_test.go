@@ -0,0 +1,124 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/rs/zerolog"
+
+	"github.com/fufuok/pkg/config"
+)
+
+func TestLogOutputLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	w := &logOutputWriter{
+		out:    zerolog.LevelWriterAdapter{Writer: &buf},
+		min:    zerolog.InfoLevel,
+		format: newLogfmtFormatter(),
+	}
+	log := zerolog.New(w)
+	log.Debug().Msg("skipped")
+	assert.Equal(t, 0, buf.Len())
+
+	log.Info().Str("k", "a b").Int("n", 1).Msg("hello world")
+	line := buf.String()
+	assert.Contains(t, zerolog.LevelFieldName+"=info", line)
+	assert.Contains(t, zerolog.MessageFieldName+`="hello world"`, line)
+	assert.Contains(t, `k="a b"`, line)
+	assert.Contains(t, "n=1", line)
+}
+
+func TestLogOutputTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	warn := int(zerolog.WarnLevel)
+	w, c, err := newLogOutput(config.LogOutput{Type: "tcp", Address: ln.Addr().String(), Format: "json", Level: &warn})
+	assert.Nil(t, err)
+	defer c.Close()
+
+	log := zerolog.New(w)
+	log.Info().Msg("skipped")
+	log.Warn().Msg("sent")
+
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Contains(t, `"sent"`, line)
+	assert.False(t, strings.Contains(line, "skipped"))
+}
+
+func TestLogOutputNonBlocking(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	// 接收方不读取数据, 写入不阻塞
+	w, c, err := newLogOutput(config.LogOutput{Type: "tcp", Address: ln.Addr().String()})
+	assert.Nil(t, err)
+	defer c.Close()
+	log := zerolog.New(w)
+	msg := strings.Repeat("x", 1024)
+	start := time.Now()
+	for range 20000 {
+		log.Warn().Msg(msg)
+	}
+	assert.True(t, time.Since(start) < LogOutputWriteTimeout)
+}
+
+func TestLogOutputSyslog(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	addr := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	w, c, err := newLogOutput(config.LogOutput{Type: "syslog", Address: addr, Tag: "tester", Format: "logfmt"})
+	assert.Nil(t, err)
+	defer c.Close()
+	log := zerolog.New(w)
+	log.Error().Msg("boom")
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 1024)
+	n, err := conn.Read(b)
+	assert.Nil(t, err)
+	msg := string(b[:n])
+	assert.True(t, strings.HasPrefix(msg, "<11>"))
+	assert.Contains(t, "tester[", msg)
+	assert.Contains(t, zerolog.MessageFieldName+"=boom", msg)
+}
+
+func TestNewLogOutputs(t *testing.T) {
+	_, _, err := newLogOutputs(config.LogConf{Outputs: []config.LogOutput{{Type: "stdout"}, {Type: "tcp"}}})
+	assert.Contains(t, "log_conf.outputs.1: address is required", err.Error())
+
+	_, _, err = newLogOutputs(config.LogConf{Outputs: []config.LogOutput{{Type: "kafka"}}})
+	assert.NotNil(t, err)
+
+	w, closers, err := newLogOutputs(config.LogConf{Outputs: []config.LogOutput{
+		{Type: "file", Address: filepath.Join(t.TempDir(), "app.log"), MaxSize: 1, Format: "json"},
+		{Type: "stderr", Format: "console", NoColor: true},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(closers))
+	log := zerolog.New(w)
+	log.Info().Msg("multi")
+	closeLogOutputs(closers)
+}
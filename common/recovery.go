@@ -1,16 +1,27 @@
 package common
 
 import (
+	"sync/atomic"
+
 	"github.com/fufuok/utils"
 )
 
 var (
 	_ utils.RecoveryCallback = RecoverAlarm
 	_ utils.RecoveryCallback = RecoverLogger
+
+	// 已恢复的崩溃次数
+	panicCount atomic.Uint64
 )
 
+// PanicCount 程序启动以来经 RecoverAlarm/RecoverLogger 恢复的崩溃次数
+func PanicCount() uint64 {
+	return panicCount.Load()
+}
+
 // RecoverAlarm 记录崩溃日志并发出报警
 func RecoverAlarm(err any, trace []byte) {
+	panicCount.Add(1)
 	info := utils.MustString(err)
 	more := utils.MustString(trace)
 	Log().Error().Str("error", info).Str("trace", more).Msg("Recovered and triggered alarm")
@@ -19,6 +30,7 @@ func RecoverAlarm(err any, trace []byte) {
 
 // RecoverLogger 记录崩溃日志
 func RecoverLogger(err any, trace []byte) {
+	panicCount.Add(1)
 	info := utils.MustString(err)
 	more := utils.MustString(trace)
 	Log().Error().Str("error", info).Str("trace", more).Msg("Recovered from panic")
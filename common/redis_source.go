@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// GetRedisSource 从 Redis 获取配置内容并更新本地文件, 需先执行 InitRedisDB(...)
+// FilesConf.Options:
+//   - key: 键名, 必填
+//   - field: Hash 字段名, 指定时使用 HGET, 否则 GET
+//   - extract: 同 config.GetHTTPSource
+//
+// 开启签名校验 (FilesConf.Verify) 时仅支持 JSON 信封模式
+func GetRedisSource(args any) error {
+	params, ok := args.(config.DataSourceArgs)
+	if !ok {
+		return fmt.Errorf("invalid data source configuration: %T", args)
+	}
+	if !RedisDBInited.Load() {
+		return errors.New("redis source: RedisDB is not initialized")
+	}
+	cfg := params.Conf
+	key := cfg.Option("key")
+	if key == "" {
+		return errors.New("redis source key cannot be empty")
+	}
+
+	var (
+		value []byte
+		err   error
+	)
+	if field := cfg.Option("field"); field != "" {
+		value, err = RedisDB.HGet(context.Background(), key, field).Bytes()
+	} else {
+		value, err = RedisDB.Get(context.Background(), key).Bytes()
+	}
+	if err != nil {
+		return fmt.Errorf("redis source %s: %w", key, err)
+	}
+
+	body, err := config.VerifyDataSource(cfg, value, nil)
+	if err != nil {
+		return err
+	}
+	body, err = config.ExtractSource(body, cfg.Option("extract"))
+	if err != nil {
+		return err
+	}
+	return config.WriteDataSource(cfg, body, config.ShouldUpdateFile(cfg.Path))
+}
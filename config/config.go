@@ -1,16 +1,18 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/fufuok/utils/conv"
-	"github.com/fufuok/utils/xcrypto"
 	"github.com/fufuok/utils/xfile"
 
 	"github.com/fufuok/pkg/json"
@@ -45,56 +47,91 @@ type MainConf struct {
 
 // SYSConf 主配置, 变量意义见配置文件中的描述及 default.go 中的默认值
 type SYSConf struct {
-	RestartMain             bool     `json:"restart_main"`
-	TimeSyncType            string   `json:"time_sync_type"`
-	WatcherInterval         string   `json:"watcher_interval"`
-	ReqDebug                bool     `json:"req_debug"`
-	ReqTimeout              string   `json:"req_timeout"`
-	ReqMaxRetries           int      `json:"req_max_retries"`
-	DebVersion              string   `json:"deb_version"`
-	CanaryDeployment        uint64   `json:"canary_deployment"`
-	SkipRemoteConfig        string   `json:"skip_remote_config"`
-	EnvFiles                []string `json:"env_files"`
-	BaseSecretValue         string   `json:"-"`
-	WatcherIntervalDuration time.Duration
-	ReqTimeoutDuration      time.Duration
+	RestartMain              bool     `json:"restart_main"`
+	TimeSyncType             string   `json:"time_sync_type"`
+	WatcherInterval          string   `json:"watcher_interval" validate:"duration"`
+	ReqDebug                 bool     `json:"req_debug"`
+	ReqTimeout               string   `json:"req_timeout" validate:"duration"`
+	ReqMaxRetries            int      `json:"req_max_retries" validate:"min=0"`
+	DebVersion               string   `json:"deb_version"`
+	CanaryDeployment         uint64   `json:"canary_deployment" validate:"max=100"`
+	UpdateStrategy           string   `json:"update_strategy" validate:"oneof=deb binary"`
+	BinaryURL                string   `json:"binary_url" validate:"url"`
+	BinarySHA256             string   `json:"binary_sha256"`
+	BinaryPublicKey          string   `json:"binary_public_key"`
+	RolloutSoak              string   `json:"rollout_soak" validate:"duration"`
+	GracefulRestart          bool     `json:"graceful_restart"`
+	GracefulTimeout          string   `json:"graceful_timeout" validate:"duration"`
+	SkipRemoteConfig         string   `json:"skip_remote_config"`
+	EnvFiles                 []string `json:"env_files"`
+	BaseSecretValue          string   `json:"-" secret:"true"`
+	BaseSecretSecondaryValue string   `json:"-" secret:"true"`
+	WatcherIntervalDuration  time.Duration
+	ReqTimeoutDuration       time.Duration
+	RolloutSoakDuration      time.Duration
+	GracefulTimeoutDuration  time.Duration
 }
 
 type LogConf struct {
-	NoColor              bool   `json:"no_color"`
-	NoPretty             bool   `json:"no_pretty"`
-	Level                int    `json:"level"`
-	File                 string `json:"file"`
-	Period               uint32 `json:"period"`
-	Burst                uint32 `json:"burst"`
-	MaxSize              int64  `json:"max_size"`
-	MaxBackups           int    `json:"max_backups"`
-	MaxAge               int    `json:"max_age"`
-	PostAPI              string `json:"post_api"`
-	PostAPIEnv           string `json:"post_api_env"`
-	PostAlarmAPI         string `json:"post_alarm_api"`
-	PostAlarmAPIEnv      string `json:"post_alarm_api_env"`
-	AlarmCode            string `json:"alarm_code"`
-	AlarmCodeEnv         string `json:"alarm_code_env"`
-	PostInterval         int    `json:"post_interval"`
-	PostBatchNum         int    `json:"post_batch_num"`
-	PostBatchMB          int    `json:"post_batch_mb"`
+	NoColor  bool `json:"no_color"`
+	NoPretty bool `json:"no_pretty"`
+	Level    int  `json:"level" validate:"min=-1,max=7"`
+	// Modules 模块日志级别, 覆盖 Level, 如: {"crontab": 0}, 见 logger.Module
+	Modules              map[string]int `json:"modules" validate:"min=-1,max=7"`
+	File                 string         `json:"file"`
+	Period               uint32         `json:"period"`
+	Burst                uint32         `json:"burst"`
+	MaxSize              int64          `json:"max_size" validate:"min=0"`
+	MaxBackups           int            `json:"max_backups" validate:"min=0"`
+	MaxAge               int            `json:"max_age" validate:"min=0"`
+	PostAPI              string         `json:"post_api" validate:"url"`
+	PostAPIEnv           string         `json:"post_api_env"`
+	PostAlarmAPI         string         `json:"post_alarm_api" validate:"url"`
+	PostAlarmAPIEnv      string         `json:"post_alarm_api_env"`
+	AlarmCode            string         `json:"alarm_code"`
+	AlarmCodeEnv         string         `json:"alarm_code_env"`
+	PostInterval         int            `json:"post_interval" validate:"min=0"`
+	PostBatchNum         int            `json:"post_batch_num" validate:"min=0"`
+	PostBatchMB          int            `json:"post_batch_mb" validate:"min=0"`
 	PeriodDuration       time.Duration
 	PostIntervalDuration time.Duration
 	PostBatchBytes       int
+	// Outputs 日志输出列表, 配置后替代默认的日志文件输出 (Debug 模式时仍输出到控制台)
+	Outputs []LogOutput `json:"outputs"`
+}
+
+// LogOutput 日志输出目标, 各输出独立设置格式, 最低级别和文件滚动
+type LogOutput struct {
+	// Type 输出类型: file, stdout, stderr, syslog (unix socket), tcp, udp
+	Type string `json:"type" validate:"oneof=file stdout stderr syslog tcp udp"`
+	// Format 日志格式: json, console, logfmt
+	// 默认: file 和 stdout/stderr 为 console (log_conf.no_pretty 时为 json), 其他为 json
+	Format string `json:"format" validate:"oneof=json console logfmt"`
+	// Level 最低日志级别, 未设置时同日志记录器级别, 低于日志记录器级别 (log_conf.level) 的日志不会输出
+	Level *int `json:"level" validate:"min=-1,max=7"`
+	// Address 输出地址: file 为文件路径 (默认: log_conf.file), syslog 为 unix socket 路径 (默认: /dev/log),
+	// tcp/udp 为 host:port, 每行一条日志
+	Address string `json:"address"`
+	// Tag syslog 标识, 默认: 应用名
+	Tag     string `json:"tag"`
+	NoColor bool   `json:"no_color"`
+	// 文件滚动设置, 未设置时同 log_conf
+	MaxSize    int64 `json:"max_size" validate:"min=0"`
+	MaxBackups int   `json:"max_backups" validate:"min=0"`
+	MaxAge     int   `json:"max_age" validate:"min=0"`
 }
 
 type WebConf struct {
 	// Name 可选服务标识, 多实例时用于日志区分, 如 "api" / "admin"
 	Name            string `json:"name"`
-	PProfAddr       string `json:"pprof_addr"`
-	ServerAddr      string `json:"server_addr"`
-	ServerHttpsAddr string `json:"server_https_addr"`
+	PProfAddr       string `json:"pprof_addr" validate:"addr"`
+	ServerAddr      string `json:"server_addr" validate:"addrs"`
+	ServerHttpsAddr string `json:"server_https_addr" validate:"addrs"`
 	// Groups 是同一进程内多组 Web 服务监听配置. 每组会复用 WebConf 中的通用默认项,
 	// 但可拥有独立 name/server_addr/server_https_addr 和路由注册函数.
 	Groups         map[string]WebConf `json:"groups"`
 	StatsPath      string             `json:"stats_path"`
-	TrustedProxies []string           `json:"trusted_proxies"`
+	TrustedProxies []string           `json:"trusted_proxies" validate:"cidrs"`
 
 	// Gin
 	TrustedPlatform string `json:"trusted_platform"`
@@ -110,7 +147,7 @@ type WebConf struct {
 	DisableKeepalive bool `json:"disable_keepalive"`
 
 	// Fiber 请求体大小限制, 0 为默认: 8 * 1024 * 1024, -1 表示不限制
-	BodyLimit int `json:"body_limit"`
+	BodyLimit int `json:"body_limit" validate:"min=-1"`
 
 	// 黑白名单中间件缓存容量配置, 键生命周期秒数
 	WhitelistLRUCapacity uint32 `json:"whitelist_lru_capacity"`
@@ -119,11 +156,11 @@ type WebConf struct {
 	BlacklistLRULifetime uint32 `json:"blacklist_lru_lifetime"`
 
 	// 同时处理的请求数限制, 调用该中间件时有效 (以 处理中 的请求为依据, -1 表示不限制)
-	RequestsLimit int32 `json:"requests_limit"`
+	RequestsLimit int32 `json:"requests_limit" validate:"min=-1"`
 
 	// 接口签名密钥生命周期和签名密钥值
-	SignTTL int64  `json:"sign_ttl"`
-	SignKey string `json:"-"`
+	SignTTL int64  `json:"sign_ttl" validate:"min=0"`
+	SignKey string `json:"-" secret:"true"`
 
 	// CertFileEnv/KeyFileEnv 为分组级独立 TLS 证书的环境变量名(可选).
 	// 分组同时指定二者时, 使用对应环境变量指向的证书覆盖继承自主配置的证书;
@@ -231,66 +268,133 @@ func NormalizeWebGroupConf(base WebConf, name string, group WebConf) WebConf {
 }
 
 type FilesConf struct {
-	Path            string `json:"path"`
-	Method          string `json:"method"`
-	SecretName      string `json:"secret_name"`
-	API             string `json:"api"`
-	Interval        int    `json:"interval"`
-	RandomWait      int    `json:"random_wait"`
-	SecretValue     string `json:"-"`
+	Path       string `json:"path"`
+	Method     string `json:"method"`
+	SecretName string `json:"secret_name"`
+	API        string `json:"api" validate:"url"`
+	Interval   int    `json:"interval" validate:"min=0"`
+	RandomWait int    `json:"random_wait" validate:"min=0"`
+	// Options 远端配置获取方法 (Method) 的专用参数, 如认证方式, 键名, 响应提取路径, 见各获取方法说明
+	Options map[string]string `json:"options"`
+	// Push 推送订阅地址, 收到变更通知后立即获取配置, 定时获取 (Interval) 作为兜底:
+	//   redis://{channel}: common.RedisDB 发布订阅频道
+	//   http(s)://...: SSE (text/event-stream) 或长轮询, 认证和请求头同 Options
+	//   其他协议 (如 WebSocket) 可通过 master.RegisterPushSubscriber 注册
+	Push string `json:"push"`
+	// Verify 远端配置内容校验 (签名, 格式), 校验失败时不覆盖本地文件
+	Verify          VerifyConf `json:"verify"`
+	SecretValue     string     `json:"-" secret:"true"`
 	GetConfDuration time.Duration
 }
 
+// Option 获取远端配置获取方法的参数, 未设置时返回默认值
+func (c FilesConf) Option(name string, defaultValue ...string) string {
+	if v := c.Options[name]; v != "" {
+		return v
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
 // LoadConfig 加载配置
+// 同时计算与上一份配置的差异, 可通过 LastDiff() 获取
 func LoadConfig() error {
-	cfg, err := readConfig()
+	// 读取配置时会修改环境变量, 密钥和名单等全局变量, 失败时全部恢复, 不留下新旧混合的状态
+	snapshot := TakeSnapshot()
+	cfg, sv, err := readConfig()
 	if err != nil {
+		snapshot.restoreState()
 		return err
 	}
 
+	// 主配置和所有配置节均解析成功后才生效
+	diff := DiffConfig(mainConf.Load(), cfg)
+	if changes := storeSections(sv); !diff.Initial {
+		diff.Changes = append(diff.Changes, changes...)
+	}
+	// 新旧配置中由密钥引用得到的值均脱敏
+	redactSecretChanges(diff.Changes, slices.Concat(secretRefPaths, sv.secretPaths))
+	secretRefPaths = sv.secretPaths
+	lastDiff.Store(diff)
 	mainConf.Store(cfg)
 
 	return nil
 }
 
-// 从主配置文件读取配置
-func readConfig() (*MainConf, error) {
+// 从主配置文件读取配置, 同时解码已注册的配置节
+func readConfig() (*MainConf, *sectionValues, error) {
+	// 各层主配置文件 (json/yaml/toml) 合并后为 JSON, AppConfigBody 为 JSON 且不参与分层合并
 	body := AppConfigBody
+	files := []string{ConfigFile}
+	var candidates []string
 	if len(body) == 0 {
 		var err error
-		body, err = os.ReadFile(ConfigFile)
+		body, files, candidates, err = mergeConfigFiles(ConfigFile)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
+	configFiles = files
+	configLayerFiles = candidates
 
 	cfg := new(MainConf)
 	if err := json.Unmarshal(body, cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	loadEnvFiles(cfg.SYSConf.EnvFiles...)
 
+	// 环境变量 (含 env 文件) 覆盖配置项, 如: FF_WEB_CONF__SERVER_ADDR=:8080
+	if err := applyEnvOverrides(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	if err := parseBaseSecret(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	// 解析密钥引用: secret://{provider}/{path}, 每次加载时重新获取
+	secretPaths, err := applySecretRefs(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 先校验原始配置值, 错误配置直接拒绝, 不再由下面的 parse* 静默修正为默认值
+	if err := Validate(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	sv, err := decodeSections(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	sv.secretPaths = append(sv.secretPaths, secretPaths...)
+
 	if err := parseSYSConfig(cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	parseLogConfig(cfg)
 	parseAlarmOnConfig(cfg)
 
 	if err := parseMainRemoteConfig(cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	parseNodeInfoConfig(cfg)
-	parseWebConfig(cfg)
+
+	if err := parseWebConfig(cfg); err != nil {
+		return nil, nil, err
+	}
 
 	if err := parseWhitelistConfig(cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := parseBlacklistConfig(cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if Debug {
@@ -298,23 +402,40 @@ func readConfig() (*MainConf, error) {
 		fmt.Printf("\nWhitelist:\n%v\n\n", Whitelist)
 		fmt.Printf("\nBlacklist:\n%v\n\n", Blacklist)
 	}
-	return cfg, nil
+	return cfg, sv, nil
 }
 
-func parseSYSConfig(cfg *MainConf) error {
+// 使用 BaseSecretKeyValue 解密基础密钥环境变量, 兼容带校验的格式, 校验失败时返回空
+func decryptBaseSecret(key string) string {
+	v, _ := DecryptSecretWith(os.Getenv(key), BaseSecretKeyValue)
+	return v
+}
+
+// 基础密钥, 在解析密钥引用前加载
+func parseBaseSecret(cfg *MainConf) error {
 	if AppBaseSecretValue != "" {
 		cfg.SYSConf.BaseSecretValue = AppBaseSecretValue
 	} else {
 		// 基础密钥: 由程序固化的密钥解密环境变量得到, 其他加密变量都使用基础密码加密
-		cfg.SYSConf.BaseSecretValue = xcrypto.GetenvDecrypt(BaseSecretEnvName, BaseSecretKeyValue)
+		cfg.SYSConf.BaseSecretValue = decryptBaseSecret(BaseSecretEnvName)
 		if cfg.SYSConf.BaseSecretValue == "" {
 			return fmt.Errorf("%s cannot be empty", BaseSecretEnvName)
 		}
 	}
+	// 次基础密钥 (可选): 轮换期间的旧密钥, 解密方式同基础密钥
+	cfg.SYSConf.BaseSecretSecondaryValue = AppBaseSecretSecondaryValue
+	if cfg.SYSConf.BaseSecretSecondaryValue == "" {
+		cfg.SYSConf.BaseSecretSecondaryValue = decryptBaseSecret(BaseSecretSecondaryEnvName)
+	}
 	// 赋值到全局变量
 	BaseSecretValue = cfg.SYSConf.BaseSecretValue
+	BaseSecretSecondaryValue = cfg.SYSConf.BaseSecretSecondaryValue
 	WebTokenSalt = cfg.SYSConf.BaseSecretValue
+	resetSecondarySecretKeys()
+	return nil
+}
 
+func parseSYSConfig(cfg *MainConf) error {
 	// 包版本格式清理
 	cfg.SYSConf.DebVersion = regexp.MustCompile(`[^\w-.=]`).ReplaceAllString(cfg.SYSConf.DebVersion, "")
 
@@ -333,6 +454,34 @@ func parseSYSConfig(cfg *MainConf) error {
 	}
 	cfg.SYSConf.ReqTimeoutDuration = dur
 	cfg.SYSConf.ReqTimeout = dur.String()
+
+	// 新版本安装后的健康观察期, 空或 0 时不检查
+	dur, err = ParseDuration(cfg.SYSConf.RolloutSoak, 0)
+	if err != nil {
+		return fmt.Errorf("parse rollout_soak err: %w", err)
+	}
+	cfg.SYSConf.RolloutSoakDuration = dur
+
+	// 平滑重启超时时间
+	dur, err = ParseDuration(cfg.SYSConf.GracefulTimeout, GracefulTimeoutDuration, time.Second)
+	if err != nil {
+		return fmt.Errorf("parse graceful_timeout err: %w", err)
+	}
+	cfg.SYSConf.GracefulTimeoutDuration = dur
+	cfg.SYSConf.GracefulTimeout = dur.String()
+
+	// 程序升级方式, 二进制下载升级时必须配置下载地址和签名公钥
+	if cfg.SYSConf.UpdateStrategy == "" {
+		cfg.SYSConf.UpdateStrategy = UpdateStrategyDeb
+	}
+	if cfg.SYSConf.UpdateStrategy == UpdateStrategyBinary {
+		if cfg.SYSConf.BinaryURL == "" {
+			return errors.New("binary_url cannot be empty when update_strategy is binary")
+		}
+		if pub, err := decodeKey(cfg.SYSConf.BinaryPublicKey); err != nil || len(pub) != ed25519.PublicKeySize {
+			return errors.New("binary_public_key: invalid ed25519 public key")
+		}
+	}
 	return nil
 }
 
@@ -341,11 +490,7 @@ func parseSYSConfig(cfg *MainConf) error {
 //nolint:cyclop
 func parseLogConfig(cfg *MainConf) {
 	// 日志级别: -1Trace 0Debug(0 或未指定该配置项) 1Info 2Warn(默认) 3Error 4Fatal 5Panic 6NoLevel 7Off
-	if cfg.LogConf.Level > 7 || cfg.LogConf.Level < -1 {
-		cfg.LogConf.Level = LogLevel
-	}
-
-	// 调试模式 Debug 日志
+	// 取值范围已由 Validate 校验, 调试模式强制 Debug 日志
 	if Debug {
 		cfg.LogConf.Level = 0
 	}
@@ -389,6 +534,45 @@ func parseLogConfig(cfg *MainConf) {
 	if cfg.LogConf.MaxAge < 1 {
 		cfg.LogConf.MaxAge = LogFileMaxAge
 	}
+
+	// 日志输出列表, 未设置项继承 log_conf
+	for i := range cfg.LogConf.Outputs {
+		out := &cfg.LogConf.Outputs[i]
+		switch out.Type {
+		case "file":
+			if out.Address == "" {
+				out.Address = cfg.LogConf.File
+			}
+			if out.MaxSize < 1 {
+				out.MaxSize = cfg.LogConf.MaxSize
+			}
+			if out.MaxBackups < 1 {
+				out.MaxBackups = cfg.LogConf.MaxBackups
+			}
+			if out.MaxAge < 1 {
+				out.MaxAge = cfg.LogConf.MaxAge
+			}
+			fallthrough
+		case "stdout", "stderr":
+			if out.Format == "" {
+				out.Format = "console"
+				if cfg.LogConf.NoPretty {
+					out.Format = "json"
+				}
+			}
+			out.NoColor = out.NoColor || cfg.LogConf.NoColor
+		case "syslog":
+			if out.Address == "" {
+				out.Address = LogSyslogAddr
+			}
+			if out.Tag == "" {
+				out.Tag = AppName
+			}
+		}
+		if out.Format == "" {
+			out.Format = "json"
+		}
+	}
 }
 
 func parseAlarmOnConfig(cfg *MainConf) {
@@ -436,7 +620,7 @@ func parseMainRemoteConfig(cfg *MainConf) error {
 	return nil
 }
 
-func parseWebConfig(cfg *MainConf) {
+func parseWebConfig(cfg *MainConf) error {
 	// 优先使用配置中的绑定参数(HTTP), 英文逗号分隔多个端口
 	if cfg.WebConf.ServerAddr == "" {
 		cfg.WebConf.ServerAddr = WebServerAddr
@@ -448,7 +632,11 @@ func parseWebConfig(cfg *MainConf) {
 	}
 
 	// 接口签名密钥和生命周期
-	cfg.WebConf.SignKey = xcrypto.GetenvDecrypt(WebSignKeyEnvName, cfg.SYSConf.BaseSecretValue)
+	signKey, err := GetenvSecret(WebSignKeyEnvName, cfg.SYSConf.BaseSecretValue)
+	if err != nil {
+		return err
+	}
+	cfg.WebConf.SignKey = signKey
 	if cfg.WebConf.SignTTL < int64(WebSignTTLMin) {
 		cfg.WebConf.SignTTL = int64(WebSignTTLDefault)
 	}
@@ -481,6 +669,7 @@ func parseWebConfig(cfg *MainConf) {
 		}
 		cfg.WebConf.Groups = groups
 	}
+	return nil
 }
 
 // resolveGroupCertFile 解析分组独立 TLS 证书.
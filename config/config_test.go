@@ -92,3 +92,26 @@ func lookupIPNets(ip net.IP, ipNets map[*net.IPNet]int64) (int64, bool) {
 	}
 	return 0, false
 }
+
+func TestParseLogOutputs(t *testing.T) {
+	cfg := new(MainConf)
+	cfg.LogConf.NoPretty = true
+	cfg.LogConf.Outputs = []LogOutput{
+		{Type: "file", MaxAge: 3},
+		{Type: "stdout"},
+		{Type: "syslog", Format: "logfmt"},
+		{Type: "udp", Address: "127.0.0.1:514"},
+	}
+	parseLogConfig(cfg)
+
+	file := cfg.LogConf.Outputs[0]
+	assert.Equal(t, cfg.LogConf.File, file.Address)
+	assert.Equal(t, cfg.LogConf.MaxSize, file.MaxSize)
+	assert.Equal(t, 3, file.MaxAge)
+	assert.Equal(t, "json", file.Format)
+	assert.Equal(t, "json", cfg.LogConf.Outputs[1].Format)
+	assert.Equal(t, LogSyslogAddr, cfg.LogConf.Outputs[2].Address)
+	assert.Equal(t, AppName, cfg.LogConf.Outputs[2].Tag)
+	assert.Equal(t, "logfmt", cfg.LogConf.Outputs[2].Format)
+	assert.Equal(t, "json", cfg.LogConf.Outputs[3].Format)
+}
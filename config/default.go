@@ -30,14 +30,19 @@ var (
 
 	// AppBaseSecretValue APP 设置的基础密钥值(可选, 优先使用)
 	AppBaseSecretValue string
+	// AppBaseSecretSecondaryValue APP 设置的次基础密钥值(可选, 优先使用)
+	AppBaseSecretSecondaryValue string
 	// AppConfigBody APP 设置的固定配置 JSON 字符串, 替代配置文件 (可选, 优先使用)
 	AppConfigBody []byte
 
+	// DryRun 只读加载配置 (配置检查工具使用): 不创建目录, 不保存节点信息备份, 不请求出口 IP 接口
+	DryRun bool
+
 	// MainConfRemoteAPIEnvName 获取主配置文件的 API URL (可选, 优先使用)
 	MainConfRemoteAPIEnvName = "MAIN_CONF_REMOTE_API"
 	// MainConfigFileEnvName 启动级主配置文件路径环境变量名, 绝对路径直接使用, 相对路径基于 ConfigPath
 	MainConfigFileEnvName = "MAIN_CONFIG_FILE"
-	// MainConfigNameEnvName 启动级主配置名称环境变量名, 仅作为 ConfigPath 下的 JSON 文件名使用
+	// MainConfigNameEnvName 启动级主配置名称环境变量名, 仅作为 ConfigPath 下的配置文件名使用 (json/yaml/toml)
 	MainConfigNameEnvName = "MAIN_CONFIG_NAME"
 	// BootstrapEnvSuffix 启动级环境文件后缀, 完整文件名为 env/{BinName}.default.env
 	BootstrapEnvSuffix = ".default.env"
@@ -59,6 +64,9 @@ var (
 	LogPath string
 	LogFile string
 
+	// LogSyslogAddr 日志输出到 syslog 的默认 unix socket 路径
+	LogSyslogAddr = "/dev/log"
+
 	// ConfigPath 主配置文件绝对路径, .env 配置文件路径
 	ConfigPath  string
 	ConfigFile  string
@@ -74,6 +82,11 @@ var (
 
 	// ReqUserAgent Request 请求名称
 	ReqUserAgent string
+
+	// HistoryPath 配置文件历史版本目录, 默认: etc/.history
+	HistoryPath string
+	// HistoryLimit 每个配置文件保留的历史版本数, <= 0 时不记录
+	HistoryLimit = 20
 )
 
 var (
@@ -98,6 +111,10 @@ var (
 	BaseSecretValue string
 	// BaseSecretEnvName 项目基础密钥 (环境变量名)
 	BaseSecretEnvName = "BASE_SECRET_KEY"
+	// BaseSecretSecondaryValue 次基础密钥值 (可选), 密钥轮换期间为旧基础密钥, 主密钥解密失败时使用
+	BaseSecretSecondaryValue string
+	// BaseSecretSecondaryEnvName 次基础密钥 (环境变量名), 加密方式同 BASE_SECRET_KEY
+	BaseSecretSecondaryEnvName = "BASE_SECRET_KEY_SECONDARY"
 	// BaseSecretKeyNameEnvName 用于在环境变量中指定上一行设置的值的键名, 而不是使用默认的: BASE_SECRET_KEY
 	BaseSecretKeyNameEnvName = "BASE_SECRET_KEY_NAME"
 
@@ -123,6 +140,13 @@ var (
 	ReqTimeoutDuration      = 30 * time.Second
 	ReqTimeoutShortDuration = 3 * time.Second
 
+	// GracefulTimeoutDuration 平滑重启时等待新进程就绪, 以及旧进程处理完已有请求的最长时间
+	GracefulTimeoutDuration = 30 * time.Second
+	// GracefulListenFdsEnvName 平滑重启时传递给新进程的监听地址列表 (环境变量名), 按顺序对应文件描述符 3, 4, ...
+	GracefulListenFdsEnvName = "GRACEFUL_LISTEN_FDS"
+	// GracefulReadyFdEnvName 新进程就绪通知管道的文件描述符 (环境变量名)
+	GracefulReadyFdEnvName = "GRACEFUL_READY_FD"
+
 	// ChanxInitCap 无限缓冲信道默认初始化缓冲大小
 	ChanxInitCap = 50
 	// ChanxMaxBufCap 无限缓冲信道最大缓冲数量, 0 为无限, 超过限制(ChanxInitCap + ChanxMaxBufCap)丢弃数据
@@ -170,6 +194,12 @@ var (
 	WebTokenSalt string
 )
 
+// InitDefaultConfig 初始化缺省变量 (程序, 配置, 环境文件路径等), 不加载配置
+// config.M.Start() 中已调用, 仅用于不经 Pipeline 直接 LoadConfig() 的场景, 如命令行工具
+func InitDefaultConfig() {
+	initDefaultConfig()
+}
+
 // 初始化缺省变量
 // .
 // ├── bin
@@ -217,6 +247,14 @@ func initDefaultConfig() {
 		EnvMainFile = filepath.Join(EnvFilePath, BinName+".env")
 	}
 
+	if SecretKeyringFile == "" {
+		SecretKeyringFile = filepath.Join(ConfigPath, "secret.keyring.json")
+	}
+
+	if HistoryPath == "" {
+		HistoryPath = filepath.Join(ConfigPath, ".history")
+	}
+
 	if NodeInfoBackupFile == "" {
 		NodeInfoBackupFile = filepath.Join(ConfigPath, "node_info.backup")
 	}
@@ -237,6 +275,9 @@ func initDefaultConfig() {
 }
 
 func makePaths() {
+	if DryRun {
+		return
+	}
 	_ = os.MkdirAll(LogPath, 0o755)
 	_ = os.MkdirAll(ConfigPath, 0o755)
 }
@@ -302,14 +343,20 @@ func resolveDefaultConfigName(configPath, binName, envName, suffix string) strin
 //
 // 优先级固定为 MAIN_CONFIG_FILE > MAIN_CONFIG_NAME > {ConfigPath}/{BinName}.json.
 // 显式 ConfigFile 的最高优先级由调用方通过“仅在 ConfigFile 为空时调用本函数”保证.
+// 配置名未带 ConfigExts 中的扩展名时, 按 .json/.yaml/.yml/.toml 顺序查找已存在的文件,
+// 都不存在时仍使用 .json, 由读取配置时暴露部署错误.
 func resolveMainConfigFile(configPath, binName string) string {
 	if v := strings.TrimSpace(os.Getenv(MainConfigFileEnvName)); v != "" {
 		return resolveDefaultConfigFile(configPath, binName, MainConfigFileEnvName, ".json")
 	}
 
 	if v := strings.TrimSpace(os.Getenv(MainConfigNameEnvName)); v != "" {
-		return resolveDefaultConfigName(configPath, binName, MainConfigNameEnvName, ".json")
+		v = filepath.Base(v)
+		if IsConfigExt(filepath.Ext(v)) {
+			return filepath.Join(configPath, v)
+		}
+		return findConfigFile(configPath, v)
 	}
 
-	return resolveDefaultConfigFile(configPath, binName, "", ".json")
+	return findConfigFile(configPath, binName)
 }
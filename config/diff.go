@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// SecretTagName 敏感配置项标记, 如: `json:"-" secret:"true"`, 差异报告和配置输出时脱敏
+const SecretTagName = "secret"
+
+// RedactedValue 脱敏后的敏感配置项值
+const RedactedValue = "******"
+
+// 最近一次 LoadConfig 的配置差异
+var lastDiff atomic.Pointer[ConfigDiff]
+
+// Change 单个配置项变化
+type Change struct {
+	// Path 配置项路径, 有 JSON 标签时使用 JSON 名称, 否则使用字段名, 如: web_conf.server_addr, sys_conf.WatcherIntervalDuration
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v => %v", c.Path, c.Old, c.New)
+}
+
+// ConfigDiff 新旧主配置差异
+type ConfigDiff struct {
+	// Initial 首次加载配置, 无旧配置可对比, 此时所有配置节都视为已变化
+	Initial bool     `json:"initial"`
+	Changes []Change `json:"changes"`
+}
+
+// LastDiff 最近一次加载配置时与上一份配置的差异, 供 Pipeline.Runtime() 判断所关注的配置节是否变化
+//
+//	if !config.LastDiff().Changed("log_conf") {
+//	    return nil
+//	}
+func LastDiff() *ConfigDiff {
+	if d := lastDiff.Load(); d != nil {
+		return d
+	}
+	return &ConfigDiff{Initial: true}
+}
+
+// Empty 配置无变化
+func (d *ConfigDiff) Empty() bool {
+	return !d.Initial && len(d.Changes) == 0
+}
+
+// Changed 指定配置节 (路径前缀) 是否有变化, 不指定时表示任意配置项有变化
+// 如: Changed("log_conf"), Changed("web_conf.groups.api"), Changed("sys_conf.deb_version")
+func (d *ConfigDiff) Changed(paths ...string) bool {
+	if d.Initial {
+		return true
+	}
+	if len(paths) == 0 {
+		return len(d.Changes) > 0
+	}
+	for _, c := range d.Changes {
+		for _, p := range paths {
+			if c.Path == p || strings.HasPrefix(c.Path, p+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Paths 有变化的配置项路径列表
+func (d *ConfigDiff) Paths() []string {
+	paths := make([]string, len(d.Changes))
+	for i, c := range d.Changes {
+		paths[i] = c.Path
+	}
+	return paths
+}
+
+// Strings 变化明细, 用于日志输出
+func (d *ConfigDiff) Strings() []string {
+	ss := make([]string, len(d.Changes))
+	for i, c := range d.Changes {
+		ss[i] = c.String()
+	}
+	return ss
+}
+
+// DiffConfig 对比新旧主配置, 敏感配置项 (secret 标签) 的值脱敏
+func DiffConfig(oldCfg, newCfg *MainConf) *ConfigDiff {
+	if oldCfg == nil {
+		return &ConfigDiff{Initial: true}
+	}
+	d := new(ConfigDiff)
+	if newCfg == nil {
+		return d
+	}
+	diffValue(reflect.ValueOf(oldCfg).Elem(), reflect.ValueOf(newCfg).Elem(), "", false, &d.Changes)
+	return d
+}
+
+//nolint:cyclop
+func diffValue(ov, nv reflect.Value, path string, secret bool, changes *[]Change) {
+	switch ov.Kind() {
+	case reflect.Struct:
+		t := ov.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name := jsonFieldName(sf)
+			if name == "" {
+				name = sf.Name
+			}
+			diffValue(ov.Field(i), nv.Field(i), joinPath(path, name), secret || sf.Tag.Get(SecretTagName) == "true", changes)
+		}
+		return
+	case reflect.Map:
+		if ov.Type().Key().Kind() == reflect.String && ov.Type().Elem().Kind() == reflect.Struct {
+			keys := make(map[string]struct{})
+			for _, k := range ov.MapKeys() {
+				keys[k.String()] = struct{}{}
+			}
+			for _, k := range nv.MapKeys() {
+				keys[k.String()] = struct{}{}
+			}
+			names := make([]string, 0, len(keys))
+			for k := range keys {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			zero := reflect.Zero(ov.Type().Elem())
+			for _, k := range names {
+				key := reflect.ValueOf(k).Convert(ov.Type().Key())
+				o, n := ov.MapIndex(key), nv.MapIndex(key)
+				if !o.IsValid() {
+					o = zero
+				}
+				if !n.IsValid() {
+					n = zero
+				}
+				diffValue(o, n, joinPath(path, k), secret, changes)
+			}
+			return
+		}
+	default:
+	}
+
+	o, n := ov.Interface(), nv.Interface()
+	if reflect.DeepEqual(o, n) {
+		return
+	}
+	if secret {
+		o, n = redact(o), redact(n)
+	}
+	*changes = append(*changes, Change{Path: path, Old: o, New: n})
+}
+
+// 空值保持为空, 便于区分 "设置" 和 "清除" 敏感配置
+func redact(v any) any {
+	if reflect.ValueOf(v).IsZero() {
+		return v
+	}
+	return RedactedValue
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestDiffConfig(t *testing.T) {
+	d := DiffConfig(nil, new(MainConf))
+	assert.True(t, d.Initial)
+	assert.True(t, d.Changed("log_conf"))
+	assert.False(t, d.Empty())
+
+	oldCfg := &MainConf{
+		SYSConf: SYSConf{BaseSecretValue: "old", WatcherIntervalDuration: time.Minute},
+		LogConf: LogConf{Level: 2},
+		WebConf: WebConf{
+			SignKey: "",
+			Groups:  map[string]WebConf{"api": {ServerAddr: ":81"}, "admin": {ServerAddr: ":82"}},
+		},
+		MainConf: FilesConf{SecretValue: "s1"},
+	}
+	newCfg := &MainConf{
+		SYSConf: SYSConf{BaseSecretValue: "new", WatcherIntervalDuration: time.Minute},
+		LogConf: LogConf{Level: 2},
+		WebConf: WebConf{
+			SignKey: "sign",
+			Groups:  map[string]WebConf{"api": {ServerAddr: ":8081"}, "cb": {ServerAddr: ":83"}},
+		},
+		MainConf:  FilesConf{SecretValue: ""},
+		Whitelist: []string{"10.0.0.0/8"},
+	}
+
+	d = DiffConfig(oldCfg, oldCfg)
+	assert.True(t, d.Empty())
+	assert.False(t, d.Changed())
+
+	d = DiffConfig(oldCfg, newCfg)
+	assert.Equal(t, []string{
+		"sys_conf.BaseSecretValue",
+		"main_conf.SecretValue",
+		"web_conf.groups.admin.server_addr",
+		"web_conf.groups.api.server_addr",
+		"web_conf.groups.cb.server_addr",
+		"web_conf.SignKey",
+		"whitelist",
+	}, d.Paths())
+	assert.True(t, d.Changed())
+	assert.True(t, d.Changed("web_conf"))
+	assert.True(t, d.Changed("web_conf.groups.api"))
+	assert.False(t, d.Changed("web_conf.groups.ap"))
+	assert.False(t, d.Changed("log_conf", "node_conf"))
+
+	// 敏感配置项脱敏, 空值保留以区分设置和清除
+	assert.Equal(t, Change{Path: "sys_conf.BaseSecretValue", Old: RedactedValue, New: RedactedValue}, d.Changes[0])
+	assert.Equal(t, Change{Path: "main_conf.SecretValue", Old: RedactedValue, New: ""}, d.Changes[1])
+	assert.Equal(t, Change{Path: "web_conf.SignKey", Old: "", New: RedactedValue}, d.Changes[5])
+	assert.Equal(t, "web_conf.groups.api.server_addr: :81 => :8081", d.Changes[3].String())
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EncryptEnvFile 使用基础密钥加密 .env 文件中的明文值, 返回已加密的变量名
+//
+// keys 为空时处理所有变量, 否则只处理指定变量. 已加密 (校验通过或可解密的旧格式值), 空值和密钥引用保持不变.
+// 注释, 空行, 变量顺序和引号保持不变. 基础密钥自身不处理. 原文件备份为 {name}.bak.
+func EncryptEnvFile(name, secret string, keys ...string) ([]string, error) {
+	if secret == "" {
+		return nil, errors.New("base secret cannot be empty")
+	}
+	return rewriteEnvFile(name, func(key, value string) (string, bool) {
+		if len(keys) > 0 && !slices.Contains(keys, key) {
+			return "", false
+		}
+		if isEncryptedWith(value, secret) {
+			return "", false
+		}
+		return EncryptSecret(value, secret), true
+	})
+}
+
+// ReencryptEnvFile 将 .env 文件中使用旧基础密钥加密的值改为使用新基础密钥加密, 返回已重新加密的变量名
+//
+// 校验通过的值及可用旧密钥解密为文本的旧格式值视为加密值, 重新加密为带校验的格式; 已使用新密钥加密 (已轮换) 及明文值保持不变.
+// 新旧密钥相同时仅将旧格式的值转换为带校验的格式, 轮换前应先转换, 旧格式的值在轮换期间仅使用基础密钥解密.
+// 注释, 空行, 变量顺序和引号保持不变. 基础密钥自身 (BASE_SECRET_KEY 等使用 BaseSecretKeyValue 加密) 不处理.
+// 原文件备份为 {name}.bak.
+func ReencryptEnvFile(name, oldSecret, newSecret string) ([]string, error) {
+	if oldSecret == "" || newSecret == "" {
+		return nil, errors.New("old and new base secret cannot be empty")
+	}
+	return rewriteEnvFile(name, func(_, value string) (string, bool) {
+		if enc, mac, ok := splitSecretMAC(value); ok {
+			if _, ok := decryptWith(enc, mac, newSecret); ok {
+				return "", false
+			}
+			plain, ok := decryptWith(enc, mac, oldSecret)
+			if !ok {
+				return "", false
+			}
+			return EncryptSecret(plain, newSecret), true
+		}
+		if oldSecret != newSecret && decryptLegacy(value, newSecret) != "" {
+			return "", false
+		}
+		plain := decryptLegacy(value, oldSecret)
+		if plain == "" {
+			return "", false
+		}
+		return EncryptSecret(plain, newSecret), true
+	})
+}
+
+// 值是否已使用指定密钥加密: 带校验的值校验通过, 或旧格式值可解密为文本
+func isEncryptedWith(value, secret string) bool {
+	if enc, mac, ok := splitSecretMAC(value); ok {
+		_, ok = decryptWith(enc, mac, secret)
+		return ok
+	}
+	return decryptLegacy(value, secret) != ""
+}
+
+// 逐行改写 .env 文件中的变量值, fn 返回新值和是否改写, 有改写时备份原文件并原子替换
+func rewriteEnvFile(name string, fn func(key, value string) (string, bool)) ([]string, error) {
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		keys []string
+		out  bytes.Buffer
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if k, v, ok := rewriteEnvLine(line, fn); ok {
+			line = v
+			keys = append(keys, k)
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(name+".bak", body, info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(name, out.Bytes(), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// 改写单行 KEY=VALUE, 支持 export 前缀和引号
+func rewriteEnvLine(line string, fn func(key, value string) (string, bool)) (key, newLine string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	k, v, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false
+	}
+	key = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(k), "export "))
+	if key == BaseSecretEnvName || key == BaseSecretSecondaryEnvName || key == os.Getenv(BaseSecretKeyNameEnvName) {
+		return "", "", false
+	}
+
+	value := strings.TrimSpace(v)
+	quote := ""
+	if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+		quote = value[:1]
+		value = value[1 : n-1]
+	}
+	if value == "" || IsSecretRef(value) {
+		return "", "", false
+	}
+	value, ok = fn(key, value)
+	if !ok {
+		return "", "", false
+	}
+	return key, k + "=" + quote + value + quote, true
+}
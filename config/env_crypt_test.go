@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/fufuok/utils/xcrypto"
+)
+
+func TestEncryptEnvFile(t *testing.T) {
+	secret := "base~~666"
+	encrypted := xcrypto.Encrypt("encrypted", secret)
+	body := "# comment\n" +
+		"REDIS_AUTH=redis12345\n" +
+		"export API_KEY='api key'\n" +
+		"DONE=" + encrypted + "\n" +
+		"PORT=8080\n" +
+		"REF=secret://env/REDIS_AUTH\n"
+	f := filepath.Join(t.TempDir(), "app.env")
+	assert.Nil(t, os.WriteFile(f, []byte(body), 0o600))
+
+	keys, err := EncryptEnvFile(f, secret, "REDIS_AUTH", "API_KEY", "DONE", "REF")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"REDIS_AUTH", "API_KEY"}, keys)
+
+	want := "# comment\n" +
+		"REDIS_AUTH=" + EncryptSecret("redis12345", secret) + "\n" +
+		"export API_KEY='" + EncryptSecret("api key", secret) + "'\n" +
+		"DONE=" + encrypted + "\n" +
+		"PORT=8080\n" +
+		"REF=secret://env/REDIS_AUTH\n"
+	got, err := os.ReadFile(f)
+	assert.Nil(t, err)
+	assert.Equal(t, want, string(got))
+
+	// 已加密的值不重复加密
+	keys, err = EncryptEnvFile(f, secret)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, keys)
+}
+
+func TestReencryptEnvFile(t *testing.T) {
+	oldKey, newKey := "old~~666", "new~~777"
+	rotated := xcrypto.Encrypt("rotated", newKey)
+	tagged := EncryptSecret("tagged", newKey)
+	body := "# comment\n\n" +
+		"REDIS_AUTH=" + xcrypto.Encrypt("redis12345", oldKey) + "\n" +
+		"TOKEN=" + EncryptSecret("token\tvalue", oldKey) + "\n" +
+		"TAGGED=" + tagged + "\n" +
+		"export API_KEY=\"" + xcrypto.Encrypt("api-key-value", oldKey) + "\"\n" +
+		"ROTATED=" + rotated + "\n" +
+		"PORT=8080\n" +
+		"REF=secret://env/REDIS_AUTH\n" +
+		BaseSecretEnvName + "=" + xcrypto.Encrypt("base", oldKey) + "\n"
+	f := filepath.Join(t.TempDir(), "app.env")
+	assert.Nil(t, os.WriteFile(f, []byte(body), 0o600))
+
+	keys, err := ReencryptEnvFile(f, oldKey, newKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"REDIS_AUTH", "TOKEN", "API_KEY"}, keys)
+
+	bak, err := os.ReadFile(f + ".bak")
+	assert.Nil(t, err)
+	assert.Equal(t, body, string(bak))
+
+	want := "# comment\n\n" +
+		"REDIS_AUTH=" + EncryptSecret("redis12345", newKey) + "\n" +
+		"TOKEN=" + EncryptSecret("token\tvalue", newKey) + "\n" +
+		"TAGGED=" + tagged + "\n" +
+		"export API_KEY=\"" + EncryptSecret("api-key-value", newKey) + "\"\n" +
+		"ROTATED=" + rotated + "\n" +
+		"PORT=8080\n" +
+		"REF=secret://env/REDIS_AUTH\n" +
+		BaseSecretEnvName + "=" + xcrypto.Encrypt("base", oldKey) + "\n"
+	got, err := os.ReadFile(f)
+	assert.Nil(t, err)
+	assert.Equal(t, want, string(got))
+
+	// 再次执行无变化
+	keys, err = ReencryptEnvFile(f, oldKey, newKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(keys))
+
+	// 新旧密钥相同时, 旧格式的值转换为带校验的格式
+	keys, err = ReencryptEnvFile(f, newKey, newKey)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ROTATED"}, keys)
+	got, err = os.ReadFile(f)
+	assert.Nil(t, err)
+	assert.Contains(t, "ROTATED="+EncryptSecret("rotated", newKey)+"\n", string(got))
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fufuok/pkg/json"
+)
+
+var (
+	// EnvOverridePrefix 配置项环境变量覆盖前缀, 为空时禁用该功能
+	// FF_WEB_CONF__SERVER_ADDR=:8080 覆盖 web_conf.server_addr
+	// FF_WEB_CONF__GROUPS__API__SERVER_ADDR=:8081 覆盖 web_conf.groups.api.server_addr
+	EnvOverridePrefix = "FF_"
+
+	// EnvOverrideSeparator 配置项路径分隔符 (各级键名中的单个下划线保持不变)
+	EnvOverrideSeparator = "__"
+
+	// 最近一次加载时生效的环境变量覆盖: 配置项路径 => 环境变量名
+	envOverrides map[string]string
+)
+
+// GetEnvOverrides 获取最近一次加载配置时生效的环境变量覆盖, 配置项路径 => 环境变量名
+func GetEnvOverrides() map[string]string {
+	return maps.Clone(envOverrides)
+}
+
+// 使用环境变量覆盖配置项, 在 loadEnvFiles 之后执行, 因此 env/*.env 文件中的变量同样生效
+//
+// 空值视为未设置: loadEnvFiles 会将从 env 文件中移除的变量置空, 热加载时该覆盖随之失效.
+// 首级路径不是主配置项的变量 (如应用自己的 FF_ 前缀变量) 忽略; 首级路径匹配但后续路径不存在,
+// 或值无法转换为配置项类型时, 返回聚合的 *ValidationError.
+// 不含 json 标签 (运行期派生) 及 json:"-" (敏感) 的字段不可覆盖.
+func applyEnvOverrides(cfg *MainConf) error {
+	overrides := make(map[string]string)
+	if EnvOverridePrefix == "" {
+		envOverrides = overrides
+		return nil
+	}
+
+	var errs []*FieldError
+	root := reflect.ValueOf(cfg).Elem()
+	for _, k := range envOverrideKeys() {
+		segs := envOverridePath(k)
+		if _, _, ok := fieldByJSONNameFold(root, segs[0]); !ok {
+			continue
+		}
+		raw := os.Getenv(k)
+		err := setConfigPath(root, segs, raw)
+		path := strings.Join(segs, ".")
+		if err != nil {
+			errs = append(errs, &FieldError{Path: path, Value: raw, Reason: "env " + k + ": " + err.Error()})
+			continue
+		}
+		overrides[path] = k
+	}
+	envOverrides = overrides
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// 排序后的非空覆盖环境变量名
+func envOverrideKeys() []string {
+	if EnvOverridePrefix == "" {
+		return nil
+	}
+	var keys []string
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if v != "" && len(k) > len(EnvOverridePrefix) && strings.HasPrefix(k, EnvOverridePrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 覆盖环境变量名转换为路径: FF_WEB_CONF__SERVER_ADDR => [WEB_CONF SERVER_ADDR]
+// 各级名称由 setConfigPath 不区分大小写匹配, 保留原大小写以匹配含大写字母的 map 键
+func envOverridePath(key string) []string {
+	return strings.Split(key[len(EnvOverridePrefix):], EnvOverrideSeparator)
+}
+
+// 按路径设置配置项值, 中间经过的 map (如 web_conf.groups) 按需创建条目
+// 字段名和已有的 map 键不区分大小写匹配, 匹配后 segs 各级替换为实际名称; 新建的 map 条目键名使用小写
+func setConfigPath(v reflect.Value, segs []string, raw string) error {
+	if len(segs) == 0 {
+		return coerceConfigValue(v, raw)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		f, name, ok := fieldByJSONNameFold(v, segs[0])
+		if !ok {
+			return unknownConfigPath(segs)
+		}
+		segs[0] = name
+		return setConfigPath(f, segs[1:], raw)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := mapKeyFold(v, segs[0])
+		segs[0] = key.String()
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := setConfigPath(elem, segs[1:], raw); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	default:
+	}
+	return unknownConfigPath(segs)
+}
+
+// 路径不存在, 未匹配的各级名称转为小写后用于错误信息
+func unknownConfigPath(segs []string) error {
+	for i := range segs {
+		segs[i] = strings.ToLower(segs[i])
+	}
+	return fmt.Errorf("unknown config path: %s", segs[0])
+}
+
+// 字符串转换为配置项类型
+// 字符串列表支持英文逗号分隔或 JSON 数组, 其他复合类型使用 JSON
+func coerceConfigValue(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool: %s", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return fmt.Errorf("invalid %s: %s", v.Kind(), raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return fmt.Errorf("invalid %s: %s", v.Kind(), raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v.OverflowFloat(n) {
+			return fmt.Errorf("invalid %s: %s", v.Kind(), raw)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			items := splitList(raw)
+			s := reflect.MakeSlice(v.Type(), len(items), len(items))
+			for i, item := range items {
+				s.Index(i).SetString(item)
+			}
+			v.Set(s)
+			return nil
+		}
+		return unmarshalConfigValue(v, raw)
+	default:
+		return unmarshalConfigValue(v, raw)
+	}
+	return nil
+}
+
+func unmarshalConfigValue(v reflect.Value, raw string) error {
+	p := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(raw), p.Interface()); err != nil {
+		return fmt.Errorf("invalid json %s: %w", v.Type(), err)
+	}
+	v.Set(p.Elem())
+	return nil
+}
+
+// 按 JSON 名称查找结构体字段, 忽略不可配置的字段
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonFieldName(t.Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// 按 JSON 名称查找结构体字段, 不区分大小写, 返回字段和实际的 JSON 名称
+func fieldByJSONNameFold(v reflect.Value, name string) (reflect.Value, string, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, "", false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if n := jsonFieldName(t.Field(i)); n != "" && strings.EqualFold(n, name) {
+			return v.Field(i), n, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+// 查找不区分大小写匹配的 map 键, 优先完全匹配, 多个匹配时取排序最前的键, 不存在时使用小写键名
+func mapKeyFold(v reflect.Value, name string) reflect.Value {
+	keyType := v.Type().Key()
+	exact := reflect.ValueOf(name).Convert(keyType)
+	if v.MapIndex(exact).IsValid() {
+		return exact
+	}
+	var found reflect.Value
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		if strings.EqualFold(k.String(), name) && (!found.IsValid() || k.String() < found.String()) {
+			found = k
+		}
+	}
+	if found.IsValid() {
+		return found
+	}
+	return reflect.ValueOf(strings.ToLower(name)).Convert(keyType)
+}
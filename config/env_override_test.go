@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Setenv("FF_WEB_CONF__SERVER_ADDR", ":8080")
+	t.Setenv("FF_WEB_CONF__GROUPS__API__SERVER_ADDR", ":8081")
+	t.Setenv("FF_WEB_CONF__TRUSTED_PROXIES", "10.0.0.0/8, 127.0.0.1")
+	t.Setenv("FF_LOG_CONF__LEVEL", "1")
+	t.Setenv("FF_LOG_CONF__NO_COLOR", "true")
+	t.Setenv("FF_SYS_CONF__CANARY_DEPLOYMENT", "30")
+	t.Setenv("FF_SYS_CONF__ENV_FILES", `["a.env","b,c.env"]`)
+	// 空值视为未设置, 非主配置项的同前缀变量忽略
+	t.Setenv("FF_LOG_CONF__FILE", "")
+	t.Setenv("FF_APP_TOKEN", "x")
+
+	cfg := &MainConf{
+		LogConf: LogConf{File: "app.log"},
+		WebConf: WebConf{Groups: map[string]WebConf{"api": {Name: "api", ServerAddr: ":81"}}},
+	}
+	assert.Nil(t, applyEnvOverrides(cfg))
+	assert.Equal(t, ":8080", cfg.WebConf.ServerAddr)
+	assert.Equal(t, WebConf{Name: "api", ServerAddr: ":8081"}, cfg.WebConf.Groups["api"])
+	assert.Equal(t, []string{"10.0.0.0/8", "127.0.0.1"}, cfg.WebConf.TrustedProxies)
+	assert.Equal(t, 1, cfg.LogConf.Level)
+	assert.True(t, cfg.LogConf.NoColor)
+	assert.Equal(t, "app.log", cfg.LogConf.File)
+	assert.Equal(t, uint64(30), cfg.SYSConf.CanaryDeployment)
+	assert.Equal(t, []string{"a.env", "b,c.env"}, cfg.SYSConf.EnvFiles)
+	assert.Equal(t, "FF_LOG_CONF__LEVEL", GetEnvOverrides()["log_conf.level"])
+	assert.Equal(t, 7, len(GetEnvOverrides()))
+
+	// 类型错误和不存在的路径均报告
+	t.Setenv("FF_LOG_CONF__LEVEL", "debug")
+	t.Setenv("FF_WEB_CONF__SIGN_KEY", "secret")
+	err := applyEnvOverrides(new(MainConf))
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, []string{"log_conf.level", "web_conf.sign_key"}, ve.Paths())
+}
+
+func TestApplyEnvOverridesMixedCaseKey(t *testing.T) {
+	t.Setenv("FF_WEB_CONF__GROUPS__AdminAPI__SERVER_ADDR", ":9090")
+	t.Setenv("FF_WEB_CONF__GROUPS__NEW__SERVER_ADDR", ":9091")
+
+	cfg := &MainConf{
+		WebConf: WebConf{Groups: map[string]WebConf{"adminAPI": {Name: "admin", ServerAddr: ":90"}}},
+	}
+	assert.Nil(t, applyEnvOverrides(cfg))
+	assert.Equal(t, WebConf{Name: "admin", ServerAddr: ":9090"}, cfg.WebConf.Groups["adminAPI"])
+	assert.Equal(t, ":9091", cfg.WebConf.Groups["new"].ServerAddr)
+	assert.Equal(t, 2, len(cfg.WebConf.Groups))
+	assert.Equal(t, "FF_WEB_CONF__GROUPS__AdminAPI__SERVER_ADDR", GetEnvOverrides()["web_conf.groups.adminAPI.server_addr"])
+}
@@ -0,0 +1,414 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imroc/req/v3"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// 远端配置获取方法名, 对应 FilesConf.Method, 由 master 注册到 common.Funcs
+const (
+	MethodDataSource = "GetDataSource"
+	MethodHTTPSource = "GetHTTPSource"
+	MethodS3Source   = "GetS3Source"
+	MethodKVSource   = "GetKVSource"
+	// MethodRedisSource 见 common.GetRedisSource
+	MethodRedisSource = "GetRedisSource"
+)
+
+// HTTP 条件请求缓存: 配置文件路径 => ETag/Last-Modified
+var sourceValidators sync.Map
+
+type sourceValidator struct {
+	api          string
+	etag         string
+	lastModified string
+}
+
+// GetHTTPSource 通过 HTTP(S) GET 获取配置内容并更新本地文件
+// 支持 ETag/If-Modified-Since 条件请求, 304 时不更新
+//
+// FilesConf.Options:
+//   - auth: 认证方式, bearer: Authorization: Bearer {secret}, basic: 用户名为 user 参数, 密码为 {secret},
+//     header: 请求头 auth_header (默认 X-Auth-Token) 值为 {secret}; 留空不认证. {secret} 为 secret_name 环境变量解密值
+//   - header.{Name}: 附加请求头, 如 "header.Accept": "application/json"
+//   - extract: JSON 响应提取路径, 如 data.config, 数组下标如 data.0; 结果为字符串时原样写入, 字符串数组按行写入, 其他写入 JSON
+//
+// 开启签名校验 (FilesConf.Verify) 时, 先校验响应内容签名, 信封模式时 extract 作用于 payload
+func GetHTTPSource(args any) error {
+	params, ok := args.(DataSourceArgs)
+	if !ok {
+		return fmt.Errorf("invalid data source configuration: %T", args)
+	}
+	cfg := params.Conf
+
+	r := req.R()
+	if err := SetSourceRequest(r, cfg); err != nil {
+		return err
+	}
+	setSourceValidator(r, cfg)
+
+	resp, err := r.Get(cfg.API)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if !resp.IsSuccessState() {
+		return fmt.Errorf("http source request failed: [%d] %s", resp.StatusCode, resp.Status)
+	}
+
+	body, err := VerifyDataSource(cfg, resp.Bytes(), resp.Header)
+	if err != nil {
+		return err
+	}
+	body, err = ExtractSource(body, cfg.Option("extract"))
+	if err != nil {
+		return err
+	}
+	if err := WriteDataSource(cfg, body, ShouldUpdateFile(cfg.Path)); err != nil {
+		return err
+	}
+	storeSourceValidator(resp, cfg)
+	return nil
+}
+
+// GetS3Source 通过 S3 兼容接口 (AWS S3, MinIO, OSS 等) GET 对象获取配置内容并更新本地文件
+// API 为对象完整 URL, 如: https://s3.us-east-1.amazonaws.com/bucket/app.json. 支持 ETag 条件请求
+//
+// FilesConf.Options:
+//   - access_key: 访问密钥 ID, 秘密访问密钥为 {secret}, 使用 AWS Signature V4 签名; 留空时匿名访问
+//   - region: 区域, 默认 us-east-1
+//   - extract: 同 GetHTTPSource
+func GetS3Source(args any) error {
+	params, ok := args.(DataSourceArgs)
+	if !ok {
+		return fmt.Errorf("invalid data source configuration: %T", args)
+	}
+	cfg := params.Conf
+
+	r := req.R()
+	setSourceHeaders(r, cfg)
+	setSourceValidator(r, cfg)
+	if ak := cfg.Option("access_key"); ak != "" {
+		u, err := url.Parse(cfg.API)
+		if err != nil {
+			return err
+		}
+		headers := signS3Request(u, ak, cfg.SecretValue, cfg.Option("region", "us-east-1"), params.Time)
+		r.SetHeaders(headers)
+	}
+
+	resp, err := r.Get(cfg.API)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if !resp.IsSuccessState() {
+		return fmt.Errorf("s3 source request failed: [%d] %s", resp.StatusCode, resp.Status)
+	}
+
+	body, err := VerifyDataSource(cfg, resp.Bytes(), resp.Header)
+	if err != nil {
+		return err
+	}
+	body, err = ExtractSource(body, cfg.Option("extract"))
+	if err != nil {
+		return err
+	}
+	if err := WriteDataSource(cfg, body, ShouldUpdateFile(cfg.Path)); err != nil {
+		return err
+	}
+	storeSourceValidator(resp, cfg)
+	return nil
+}
+
+// GetKVSource 通过 Consul/etcd 风格的 HTTP KV 接口获取配置内容并更新本地文件
+// API 为服务地址, 如: http://127.0.0.1:8500
+//
+// FilesConf.Options:
+//   - kv: consul (默认) 或 etcd (v3 gRPC gateway)
+//   - key: 键名, 必填
+//   - dc: Consul 数据中心 (可选)
+//   - extract: 同 GetHTTPSource, 作用于键值
+//
+// 认证: Consul 使用请求头 X-Consul-Token: {secret}, etcd 使用 Authorization: {secret}
+// 开启签名校验 (FilesConf.Verify) 时仅支持 JSON 信封模式
+func GetKVSource(args any) error {
+	params, ok := args.(DataSourceArgs)
+	if !ok {
+		return fmt.Errorf("invalid data source configuration: %T", args)
+	}
+	cfg := params.Conf
+	key := cfg.Option("key")
+	if key == "" {
+		return errors.New("kv source key cannot be empty")
+	}
+
+	var (
+		value []byte
+		err   error
+	)
+	switch kv := cfg.Option("kv", "consul"); kv {
+	case "consul":
+		value, err = getConsulKV(cfg, key)
+	case "etcd":
+		value, err = getEtcdKV(cfg, key)
+	default:
+		return fmt.Errorf("unknown kv source: %s", kv)
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := VerifyDataSource(cfg, value, nil)
+	if err != nil {
+		return err
+	}
+	body, err = ExtractSource(body, cfg.Option("extract"))
+	if err != nil {
+		return err
+	}
+	return WriteDataSource(cfg, body, ShouldUpdateFile(cfg.Path))
+}
+
+func getConsulKV(cfg FilesConf, key string) ([]byte, error) {
+	r := req.R().SetQueryParam("raw", "true")
+	if cfg.SecretValue != "" {
+		r.SetHeader("X-Consul-Token", cfg.SecretValue)
+	}
+	if dc := cfg.Option("dc"); dc != "" {
+		r.SetQueryParam("dc", dc)
+	}
+	setSourceHeaders(r, cfg)
+	resp, err := r.Get(strings.TrimRight(cfg.API, "/") + "/v1/kv/" + strings.TrimLeft(key, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("consul kv request failed: [%d] %s", resp.StatusCode, key)
+	}
+	return resp.Bytes(), nil
+}
+
+func getEtcdKV(cfg FilesConf, key string) ([]byte, error) {
+	var res struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	r := req.R().
+		SetBody(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key))}).
+		SetSuccessResult(&res)
+	if cfg.SecretValue != "" {
+		r.SetHeader("Authorization", cfg.SecretValue)
+	}
+	setSourceHeaders(r, cfg)
+	resp, err := r.Post(strings.TrimRight(cfg.API, "/") + "/v3/kv/range")
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("etcd kv request failed: [%d] %s", resp.StatusCode, key)
+	}
+	if len(res.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd kv key not found: %s", key)
+	}
+	return base64.StdEncoding.DecodeString(res.Kvs[0].Value)
+}
+
+// ExtractSource 按路径提取 JSON 内容, path 为空时原样返回
+// 结果为字符串时返回字符串内容, 字符串数组按行拼接, 其他值返回 JSON
+func ExtractSource(body []byte, path string) ([]byte, error) {
+	if path == "" {
+		return body, nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, fmt.Errorf("extract %s: %w", path, err)
+	}
+	for _, seg := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]any:
+			val, ok := x[seg]
+			if !ok {
+				return nil, fmt.Errorf("extract %s: %s not found", path, seg)
+			}
+			v = val
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, fmt.Errorf("extract %s: invalid index %s", path, seg)
+			}
+			v = x[i]
+		default:
+			return nil, fmt.Errorf("extract %s: %s not found", path, seg)
+		}
+	}
+
+	switch x := v.(type) {
+	case string:
+		return []byte(x), nil
+	case []any:
+		lines := make([]string, 0, len(x))
+		for _, item := range x {
+			s, ok := item.(string)
+			if !ok {
+				return json.Marshal(v)
+			}
+			lines = append(lines, s)
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// SetSourceRequest 按 FilesConf.Options 设置请求认证 (auth) 和附加请求头 (header.{Name}), 见 GetHTTPSource
+func SetSourceRequest(r *req.Request, cfg FilesConf) error {
+	setSourceHeaders(r, cfg)
+	switch auth := cfg.Option("auth"); auth {
+	case "":
+	case "bearer":
+		r.SetBearerAuthToken(cfg.SecretValue)
+	case "basic":
+		r.SetBasicAuth(cfg.Option("user"), cfg.SecretValue)
+	case "header":
+		r.SetHeader(cfg.Option("auth_header", "X-Auth-Token"), cfg.SecretValue)
+	default:
+		return fmt.Errorf("unknown source auth: %s", auth)
+	}
+	return nil
+}
+
+func setSourceHeaders(r *req.Request, cfg FilesConf) {
+	for k, v := range cfg.Options {
+		if name, ok := strings.CutPrefix(k, "header."); ok && name != "" {
+			r.SetHeader(name, v)
+		}
+	}
+}
+
+// 条件请求, 仅在本地文件存在且 API 未变化时使用
+func setSourceValidator(r *req.Request, cfg FilesConf) {
+	v, ok := sourceValidators.Load(cfg.Path)
+	if !ok {
+		return
+	}
+	sv := v.(sourceValidator)
+	if sv.api != cfg.API || !isFile(cfg.Path) {
+		return
+	}
+	if sv.etag != "" {
+		r.SetHeader("If-None-Match", sv.etag)
+	}
+	if sv.lastModified != "" {
+		r.SetHeader("If-Modified-Since", sv.lastModified)
+	}
+}
+
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+func storeSourceValidator(resp *req.Response, cfg FilesConf) {
+	sv := sourceValidator{
+		api:          cfg.API,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	if sv.etag == "" && sv.lastModified == "" {
+		sourceValidators.Delete(cfg.Path)
+		return
+	}
+	sourceValidators.Store(cfg.Path, sv)
+}
+
+// AWS Signature V4 签名 (GET, 空请求体), 返回需要附加的请求头
+func signS3Request(u *url.URL, accessKey, secretKey, region string, t time.Time) map[string]string {
+	const (
+		algorithm   = "AWS4-HMAC-SHA256"
+		service     = "s3"
+		emptyHash   = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+		signedNames = "host;x-amz-content-sha256;x-amz-date"
+	)
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+
+	uri := u.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		uri,
+		canonicalQuery(u.Query()),
+		"host:" + u.Host + "\nx-amz-content-sha256:" + emptyHash + "\nx-amz-date:" + amzDate + "\n",
+		signedNames,
+		emptyHash,
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	stringToSign := algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return map[string]string{
+		"X-Amz-Date":           amzDate,
+		"X-Amz-Content-Sha256": emptyHash,
+		"Authorization": algorithm + " Credential=" + accessKey + "/" + scope +
+			", SignedHeaders=" + signedNames + ", Signature=" + signature,
+	}
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := q[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// RFC 3986 编码, 空格为 %20
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
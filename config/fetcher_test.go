@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestGetHTTPSource(t *testing.T) {
+	var hits, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("Authorization") != "Bearer token~~1" || r.Header.Get("X-Env") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"code":0,"data":{"config":{"name":"ff"},"ips":["1.1.1.1","2.2.2.2"]}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := FilesConf{
+		Path:        filepath.Join(dir, "remote.json"),
+		API:         srv.URL,
+		SecretValue: "token~~1",
+		Options:     map[string]string{"auth": "bearer", "header.X-Env": "test", "extract": "data.config"},
+	}
+	assert.Nil(t, GetHTTPSource(DataSourceArgs{Conf: cfg}))
+	b, _ := os.ReadFile(cfg.Path)
+	assert.Equal(t, `{"name":"ff"}`, string(b))
+
+	// 条件请求
+	assert.Nil(t, GetHTTPSource(DataSourceArgs{Conf: cfg}))
+	assert.Equal(t, 2, hits)
+	assert.Equal(t, 1, notModified)
+
+	// 字符串数组按行写入
+	cfg.Path = filepath.Join(dir, "ips.txt")
+	cfg.Options["extract"] = "data.ips"
+	assert.Nil(t, GetHTTPSource(DataSourceArgs{Conf: cfg}))
+	b, _ = os.ReadFile(cfg.Path)
+	assert.Equal(t, "1.1.1.1\n2.2.2.2", string(b))
+
+	cfg.Path = filepath.Join(dir, "none.txt")
+	cfg.Options["extract"] = "data.none"
+	assert.NotNil(t, GetHTTPSource(DataSourceArgs{Conf: cfg}))
+
+	cfg.SecretValue = "wrong"
+	cfg.Path = filepath.Join(dir, "none.json")
+	assert.NotNil(t, GetHTTPSource(DataSourceArgs{Conf: cfg}))
+	_, err := os.Stat(cfg.Path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetS3Source(t *testing.T) {
+	var auth, amzDate string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, amzDate = r.Header.Get("Authorization"), r.Header.Get("X-Amz-Date")
+		_, _ = w.Write([]byte("a: 1\n"))
+	}))
+	defer srv.Close()
+
+	cfg := FilesConf{
+		Path:        filepath.Join(t.TempDir(), "app.yaml"),
+		API:         srv.URL + "/bucket/app.yaml",
+		SecretValue: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+		Options:     map[string]string{"access_key": "AKIDEXAMPLE"},
+	}
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Nil(t, GetS3Source(DataSourceArgs{Time: ts, Conf: cfg}))
+	assert.Equal(t, "20260102T030405Z", amzDate)
+	assert.True(t, strings.HasPrefix(auth,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260102/us-east-1/s3/aws4_request, "+
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="))
+	b, _ := os.ReadFile(cfg.Path)
+	assert.Equal(t, "a: 1\n", string(b))
+
+	// 签名确定
+	u, _ := url.Parse(cfg.API)
+	h := signS3Request(u, "AKIDEXAMPLE", cfg.SecretValue, "us-east-1", ts)
+	assert.Equal(t, auth, h["Authorization"])
+}
+
+func TestGetKVSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/app/config":
+			if r.URL.Query().Get("raw") != "true" || r.Header.Get("X-Consul-Token") != "acl" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"from":"consul"}`))
+		case "/v3/kv/range":
+			body, _ := io.ReadAll(r.Body)
+			key := base64.StdEncoding.EncodeToString([]byte("app/config"))
+			if !strings.Contains(string(body), key) {
+				_, _ = w.Write([]byte(`{}`))
+				return
+			}
+			v := base64.StdEncoding.EncodeToString([]byte(`{"from":"etcd"}`))
+			_, _ = w.Write([]byte(`{"kvs":[{"value":"` + v + `"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := FilesConf{
+		Path:        filepath.Join(dir, "consul.json"),
+		API:         srv.URL,
+		SecretValue: "acl",
+		Options:     map[string]string{"key": "app/config"},
+	}
+	assert.Nil(t, GetKVSource(DataSourceArgs{Conf: cfg}))
+	b, _ := os.ReadFile(cfg.Path)
+	assert.Equal(t, `{"from":"consul"}`, string(b))
+
+	cfg.Path = filepath.Join(dir, "etcd.json")
+	cfg.Options["kv"] = "etcd"
+	assert.Nil(t, GetKVSource(DataSourceArgs{Conf: cfg}))
+	b, _ = os.ReadFile(cfg.Path)
+	assert.Equal(t, `{"from":"etcd"}`, string(b))
+
+	cfg.Options["key"] = "none"
+	assert.NotNil(t, GetKVSource(DataSourceArgs{Conf: cfg}))
+	delete(cfg.Options, "key")
+	assert.NotNil(t, GetKVSource(DataSourceArgs{Conf: cfg}))
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// 配置文件格式, 由文件扩展名决定
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// ConfigExts 支持的配置文件扩展名, 未显式指定扩展名时按此顺序查找主配置文件
+var ConfigExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// ConfigFormat 根据文件扩展名得到配置格式, 未知扩展名 (含无扩展名) 视为 JSON
+func ConfigFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// IsConfigExt 是否为支持的配置文件扩展名
+func IsConfigExt(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, e := range ConfigExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigToJSON 按文件扩展名将 YAML/TOML 配置内容转换为 JSON, JSON 内容原样返回
+// 所有格式最终都经由 JSON 解码到配置结构体, 保证字段名 (json 标签) 和取值语义一致
+func ConfigToJSON(name string, body []byte) ([]byte, error) {
+	switch ConfigFormat(name) {
+	case FormatYAML:
+		js, err := yaml.YAMLToJSON(body)
+		if err != nil {
+			return nil, fmt.Errorf("parse yaml %s err: %w", name, err)
+		}
+		return js, nil
+	case FormatTOML:
+		var m map[string]any
+		if err := toml.Unmarshal(body, &m); err != nil {
+			return nil, fmt.Errorf("parse toml %s err: %w", name, err)
+		}
+		return json.Marshal(m)
+	default:
+		return body, nil
+	}
+}
+
+// UnmarshalConfig 按文件扩展名 (json/yaml/toml) 解码配置内容到 v
+func UnmarshalConfig(name string, body []byte, v any) error {
+	js, err := ConfigToJSON(name, body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, v)
+}
+
+// 在 configPath 下按 ConfigExts 顺序查找已存在的 {name}{ext} 配置文件, 都不存在时使用 .json
+func findConfigFile(configPath, name string) string {
+	for _, ext := range ConfigExts {
+		f := filepath.Join(configPath, name+ext)
+		if _, err := os.Stat(f); err == nil {
+			return f
+		}
+	}
+	return filepath.Join(configPath, name+ConfigExts[0])
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// TestUnmarshalConfigFormats 验证 json/yaml/toml 解码结果完全一致 (均经由 json 标签解码).
+func TestUnmarshalConfigFormats(t *testing.T) {
+	bodies := map[string]string{
+		"app.json": `{
+  "sys_conf": {"watcher_interval": "1m", "env_files": ["a.env", "b.env"]},
+  "log_conf": {"level": 1, "no_color": true},
+  "web_conf": {"server_addr": ":80", "groups": {"api": {"server_addr": ":81"}}}
+}`,
+		"app.yaml": `
+# 注释
+sys_conf:
+  watcher_interval: 1m
+  env_files: [a.env, b.env]
+log_conf:
+  level: 1
+  no_color: true
+web_conf:
+  server_addr: ":80"
+  groups:
+    api:
+      server_addr: ":81"
+`,
+		"app.TOML": `
+# 注释
+[sys_conf]
+watcher_interval = "1m"
+env_files = ["a.env", "b.env"]
+
+[log_conf]
+level = 1
+no_color = true
+
+[web_conf]
+server_addr = ":80"
+
+[web_conf.groups.api]
+server_addr = ":81"
+`,
+	}
+
+	var want []byte
+	for _, name := range []string{"app.json", "app.yaml", "app.TOML"} {
+		cfg := new(MainConf)
+		assert.Nil(t, UnmarshalConfig(name, []byte(bodies[name]), cfg))
+		got := json.MustJSON(cfg)
+		if want == nil {
+			want = got
+			continue
+		}
+		assert.Equal(t, string(want), string(got), name)
+	}
+
+	assert.Equal(t, FormatYAML, ConfigFormat("a.YML"))
+	assert.Equal(t, FormatJSON, ConfigFormat("a"))
+	assert.NotNil(t, UnmarshalConfig("bad.yaml", []byte("a: [1"), new(MainConf)))
+	assert.NotNil(t, UnmarshalConfig("bad.toml", []byte("a = "), new(MainConf)))
+}
+
+// TestResolveMainConfigFileExt 验证未指定扩展名时按 ConfigExts 顺序查找已存在的主配置文件.
+func TestResolveMainConfigFileExt(t *testing.T) {
+	configPath := t.TempDir()
+	t.Setenv(MainConfigFileEnvName, "")
+	t.Setenv(MainConfigNameEnvName, "")
+
+	assert.Equal(t, filepath.Join(configPath, "app.json"), resolveMainConfigFile(configPath, "app"))
+
+	assert.Nil(t, os.WriteFile(filepath.Join(configPath, "app.toml"), nil, 0o600))
+	assert.Equal(t, filepath.Join(configPath, "app.toml"), resolveMainConfigFile(configPath, "app"))
+
+	assert.Nil(t, os.WriteFile(filepath.Join(configPath, "app.yml"), nil, 0o600))
+	assert.Equal(t, filepath.Join(configPath, "app.yml"), resolveMainConfigFile(configPath, "app"))
+
+	t.Setenv(MainConfigNameEnvName, "gray")
+	assert.Equal(t, filepath.Join(configPath, "gray.json"), resolveMainConfigFile(configPath, "app"))
+
+	t.Setenv(MainConfigNameEnvName, "gray.yaml")
+	assert.Equal(t, filepath.Join(configPath, "gray.yaml"), resolveMainConfigFile(configPath, "app"))
+}
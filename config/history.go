@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fufuok/utils/xhash"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// 配置文件历史版本来源
+const (
+	// HistorySourceLocal 本地修改 (运维编辑或部署), 由文件监控发现
+	HistorySourceLocal = "local"
+	// HistorySourceRemote 远端配置获取, 完整来源为: remote:{FilesConf.Method}
+	HistorySourceRemote = "remote"
+	// HistorySourceRevert 回滚, 完整来源为: revert:{版本 ID}
+	HistorySourceRevert = "revert"
+
+	historyIndexFile = "index.json"
+	// 跨进程锁文件, 程序运行时记录版本与命令行工具恢复版本互斥
+	historyLockFile = ".lock"
+)
+
+// ErrHistoryNotFound 配置文件或历史版本不存在
+var ErrHistoryNotFound = errors.New("config history not found")
+
+// History 配置文件历史版本, 保存在 HistoryPath 目录, 每个文件保留最近 HistoryLimit 个版本
+var History = new(HistoryStore)
+
+// HistoryStore 配置文件历史版本存储
+// 每个文件一个子目录 (文件绝对路径的哈希), 内含版本索引 index.json 和各版本内容
+type HistoryStore struct {
+	// Dir 存储目录, 为空时使用 HistoryPath, 均为空时不记录
+	Dir string
+	// Limit 每个文件保留的版本数, 为 0 时使用 HistoryLimit
+	Limit int
+
+	mu sync.Mutex
+}
+
+// HistoryVersion 配置文件历史版本
+type HistoryVersion struct {
+	ID     string    `json:"id"`
+	File   string    `json:"file"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Hash   string    `json:"hash"`
+	Size   int       `json:"size"`
+}
+
+// Record 记录文件当前内容, 与最近版本相同时忽略, 文件不存在时忽略
+func (h *HistoryStore) Record(file, source string) (*HistoryVersion, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return h.RecordContent(file, body, source)
+}
+
+// RecordContent 记录文件内容为新版本, 与最近版本相同时忽略 (返回 nil)
+func (h *HistoryStore) RecordContent(file string, body []byte, source string) (*HistoryVersion, error) {
+	if h.limit() <= 0 || h.dir() == "" {
+		return nil, nil
+	}
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := h.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return h.recordContent(file, body, source)
+}
+
+// 记录版本, 调用方持有锁
+func (h *HistoryStore) recordContent(file string, body []byte, source string) (*HistoryVersion, error) {
+	limit := h.limit()
+	dir := h.fileDir(file)
+	versions, err := readHistoryIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	hash := xhash.MD5BytesHex(body)
+	if n := len(versions); n > 0 && versions[n-1].Hash == hash {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	v := HistoryVersion{
+		ID:     now.Format("20060102150405.000000") + "-" + hash[:8],
+		File:   file,
+		Time:   now,
+		Source: source,
+		Hash:   hash,
+		Size:   len(body),
+	}
+	if err := os.WriteFile(filepath.Join(dir, v.ID), body, 0o600); err != nil {
+		return nil, err
+	}
+	versions = append(versions, v)
+
+	// 清理超出数量的旧版本
+	if n := len(versions) - limit; n > 0 {
+		for _, old := range versions[:n] {
+			_ = os.Remove(filepath.Join(dir, old.ID))
+		}
+		versions = slices.Clone(versions[n:])
+	}
+	if err := writeFileAtomic(filepath.Join(dir, historyIndexFile), json.MustJSONIndent(versions), 0o600); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Files 有历史版本的文件列表
+func (h *HistoryStore) Files() ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries, err := os.ReadDir(h.dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		versions, err := readHistoryIndex(filepath.Join(h.dir(), e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 {
+			files = append(files, versions[0].File)
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// List 文件的历史版本, 最新版本在前
+func (h *HistoryStore) List(file string) ([]HistoryVersion, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	versions, err := readHistoryIndex(h.fileDir(file))
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// Show 获取文件指定历史版本的内容
+func (h *HistoryStore) Show(file, id string) ([]byte, *HistoryVersion, error) {
+	versions, err := h.List(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range versions {
+		v := versions[i]
+		if v.ID != id {
+			continue
+		}
+		body, err := os.ReadFile(filepath.Join(h.fileDir(v.File), v.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		return body, &v, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s@%s", ErrHistoryNotFound, file, id)
+}
+
+// Revert 将文件恢复为指定历史版本的内容, 生效方式同本地修改 (由文件监控热加载)
+// 恢复前记录当前内容, 恢复后的内容记录为新版本. 恢复期间持有跨进程锁, 与运行中程序的版本记录互斥
+func (h *HistoryStore) Revert(file, id string) (*HistoryVersion, error) {
+	body, v, err := h.Show(file, id)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := h.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	current, err := os.ReadFile(v.File)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if _, err := h.recordContent(v.File, current, HistorySourceLocal); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeFileAtomic(v.File, body, 0o600); err != nil {
+		return nil, err
+	}
+	nv, err := h.recordContent(v.File, body, HistorySourceRevert+":"+id)
+	if err != nil {
+		return nil, err
+	}
+	if nv == nil {
+		// 当前内容与目标版本一致
+		return v, nil
+	}
+	return nv, nil
+}
+
+// 加锁: 进程内互斥锁和存储目录下的跨进程文件锁
+func (h *HistoryStore) lock() (func(), error) {
+	h.mu.Lock()
+	if err := os.MkdirAll(h.dir(), 0o700); err != nil {
+		h.mu.Unlock()
+		return nil, err
+	}
+	unlock, err := lockFile(filepath.Join(h.dir(), historyLockFile))
+	if err != nil {
+		h.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		h.mu.Unlock()
+	}, nil
+}
+
+// RedactHistoryContent 历史版本内容脱敏, 供管理接口输出
+// env 文件 (.env 扩展名或 sys_conf.env_files) 可能包含明文或加密的密钥, 变量值均替换为 RedactedValue, 保留注释和变量名
+func RedactHistoryContent(file string, body []byte) []byte {
+	if !isEnvFile(file) {
+		return body
+	}
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(string(body), "\n") {
+		trimmed := strings.TrimSpace(line)
+		k, _, found := strings.Cut(line, "=")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || !found {
+			out.WriteString(line)
+			continue
+		}
+		out.WriteString(k + "=" + RedactedValue)
+		if strings.HasSuffix(line, "\n") {
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
+
+func isEnvFile(file string) bool {
+	if filepath.Ext(file) == ".env" {
+		return true
+	}
+	abs, err := filepath.Abs(file)
+	return err == nil && slices.Contains(GetEnvFiles(), abs)
+}
+
+func (h *HistoryStore) dir() string {
+	if h.Dir != "" {
+		return h.Dir
+	}
+	return HistoryPath
+}
+
+func (h *HistoryStore) limit() int {
+	if h.Limit != 0 {
+		return h.Limit
+	}
+	return HistoryLimit
+}
+
+func (h *HistoryStore) fileDir(absFile string) string {
+	return filepath.Join(h.dir(), xhash.MD5Hex(absFile)[:16])
+}
+
+func readHistoryIndex(dir string) ([]HistoryVersion, error) {
+	body, err := os.ReadFile(filepath.Join(dir, historyIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var versions []HistoryVersion
+	if err := json.Unmarshal(body, &versions); err != nil {
+		return nil, fmt.Errorf("invalid history index %s: %w", dir, err)
+	}
+	return versions, nil
+}
+
+// 写入临时文件后替换, 保留原文件权限
+func writeFileAtomic(name string, body []byte, perm os.FileMode) error {
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
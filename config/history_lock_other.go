@@ -0,0 +1,8 @@
+//go:build !unix
+
+package config
+
+// 不支持文件锁的平台仅使用进程内互斥锁
+func lockFile(string) (func(), error) {
+	return func() {}, nil
+}
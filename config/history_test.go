@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestHistoryStore(t *testing.T) {
+	dir := t.TempDir()
+	h := &HistoryStore{Dir: filepath.Join(dir, ".history"), Limit: 3}
+	file := filepath.Join(dir, "app.json")
+
+	v, err := h.Record(file, HistorySourceLocal)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	assert.Nil(t, os.WriteFile(file, []byte(`{"v":1}`), 0o644))
+	v1, err := h.Record(file, HistorySourceLocal)
+	assert.Nil(t, err)
+	assert.Equal(t, HistorySourceLocal, v1.Source)
+	// 内容未变化时不记录
+	v, err = h.Record(file, HistorySourceLocal)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	for _, s := range []string{`{"v":2}`, `{"v":3}`, `{"v":4}`} {
+		_, err = h.RecordContent(file, []byte(s), HistorySourceRemote+":GetHTTPSource")
+		assert.Nil(t, err)
+	}
+	versions, err := h.List(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(versions))
+	assert.Equal(t, "remote:GetHTTPSource", versions[0].Source)
+
+	// 超出数量的旧版本已清理
+	_, _, err = h.Show(file, v1.ID)
+	assert.True(t, errors.Is(err, ErrHistoryNotFound))
+	body, _, err := h.Show(file, versions[2].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"v":2}`, string(body))
+
+	// 回滚: 先记录当前本地内容, 恢复后记录为新版本, 保留文件权限
+	nv, err := h.Revert(file, versions[2].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, HistorySourceRevert+":"+versions[2].ID, nv.Source)
+	body, _ = os.ReadFile(file)
+	assert.Equal(t, `{"v":2}`, string(body))
+	info, _ := os.Stat(file)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	versions, _ = h.List(file)
+	assert.Equal(t, `{"v":1}`, string(mustShow(t, h, file, versions[1].ID)))
+
+	files, err := h.Files()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{file}, files)
+}
+
+func mustShow(t *testing.T, h *HistoryStore, file, id string) []byte {
+	body, _, err := h.Show(file, id)
+	assert.Nil(t, err)
+	return body
+}
+
+func TestRedactHistoryContent(t *testing.T) {
+	body := []byte("# redis\nREDIS_AUTH=abc.123\n\nexport API_KEY='x'\n")
+	assert.Equal(t, "# redis\nREDIS_AUTH=******\n\nexport API_KEY=******\n", string(RedactHistoryContent("/opt/app/env/app.env", body)))
+	assert.Equal(t, string(body), string(RedactHistoryContent("/opt/app/etc/app.json", body)))
+}
+
+func TestHistoryLock(t *testing.T) {
+	dir := t.TempDir()
+	h := &HistoryStore{Dir: filepath.Join(dir, ".history"), Limit: 3}
+	file := filepath.Join(dir, "app.json")
+
+	// 其他进程 (命令行工具) 持有锁时, 记录版本等待锁释放
+	assert.Nil(t, os.MkdirAll(h.dir(), 0o700))
+	unlock, err := lockFile(filepath.Join(h.dir(), historyLockFile))
+	assert.Nil(t, err)
+	done := make(chan struct{})
+	go func() {
+		_, _ = h.RecordContent(file, []byte(`{"v":1}`), HistorySourceLocal)
+		close(done)
+	}()
+	select {
+	case <-done:
+		if runtime.GOOS != "windows" {
+			t.Fatal("record should wait for the lock")
+		}
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-done
+	versions, err := h.List(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(versions))
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/fufuok/utils/xcrypto"
 	"github.com/fufuok/utils/xfile"
 )
 
@@ -63,13 +62,21 @@ func ParseDuration(s string, defDur time.Duration, minDur ...time.Duration) (tim
 
 // ParseRemoteFileConfig 解析远端配置获取配置项
 func ParseRemoteFileConfig(cfg *FilesConf, secret string) error {
-	// 远程获取配置 API, 解密 SecretName
+	// 远程获取配置 API, 解密 SecretName (环境变量值可为密钥引用)
 	if cfg.SecretName != "" {
-		cfg.SecretValue = xcrypto.GetenvDecrypt(cfg.SecretName, secret)
+		value, err := GetenvSecret(cfg.SecretName, secret)
+		if err != nil {
+			return err
+		}
+		cfg.SecretValue = value
 		if cfg.SecretValue == "" {
 			return fmt.Errorf("%s cannot be empty", cfg.SecretName)
 		}
 	}
+	// 远端配置内容签名校验
+	if err := parseVerifyConfig(&cfg.Verify, secret); err != nil {
+		return err
+	}
 	// 每次获取远程配置的时间间隔, < 30 秒则禁用该功能
 	if cfg.Interval >= 30 {
 		cfg.GetConfDuration = time.Duration(cfg.Interval) * time.Second
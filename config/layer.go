@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fufuok/pkg/json"
+)
+
+var (
+	// MainConfigEnvEnvName 配置环境名 (如机房/集群) 的环境变量名, 优先于 ConfigEnv
+	MainConfigEnvEnvName = "MAIN_CONFIG_ENV"
+
+	// ConfigEnv 配置环境名, 可由应用指定, 非空时合并 {BinName}.{ConfigEnv}.json 环境层配置
+	ConfigEnv string
+
+	// NodeConfigPrefix 节点层配置文件名前缀: {BinName}.node-{node_id}.json
+	NodeConfigPrefix = "node-"
+
+	// 参与合并的主配置文件列表
+	configFiles []string
+
+	// 各分层所有可能的配置文件 (含尚不存在的), 用于监控后创建的分层文件
+	configLayerFiles []string
+)
+
+// GetConfigFiles 获取最近一次加载时参与合并的主配置文件列表 (基础配置在前)
+func GetConfigFiles() []string {
+	if len(configFiles) == 0 {
+		return []string{ConfigFile}
+	}
+	return slices.Clone(configFiles)
+}
+
+// GetConfigLayerFiles 获取最近一次加载时各分层 (环境, 主机, 节点) 所有扩展名的候选配置文件, 含尚不存在的文件
+// 需监控这些文件, 以便分层文件在启动后创建时也能触发热加载
+func GetConfigLayerFiles() []string {
+	return slices.Clone(configLayerFiles)
+}
+
+// 按顺序合并主配置各层, 后者覆盖前者: 对象深度合并, 数组及其他值整体替换
+//  1. 基础配置: etc/ffapp.json
+//  2. 环境层:   etc/ffapp.{MAIN_CONFIG_ENV}.json
+//  3. 主机层:   etc/ffapp.{hostname}.json
+//  4. 节点层:   etc/ffapp.node-{node_id}.json, node_id 取自合并后 node_conf.node_info_file 指向的节点信息文件
+//
+// 各层均支持 json/yaml/toml 格式, 扩展名按 ConfigExts 顺序查找. 除基础配置外, 不存在的层忽略.
+// 返回合并后的 JSON 内容, 参与合并的文件列表和各分层所有可能的候选文件.
+func mergeConfigFiles(base string) (body []byte, files, candidates []string, err error) {
+	merged, err := readConfigMap(base)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	files = []string{base}
+
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	merge := func(suffix string) error {
+		suffix = filepath.Base(strings.TrimSpace(suffix))
+		if suffix == "" || suffix == "." || suffix == string(filepath.Separator) {
+			return nil
+		}
+		for _, e := range ConfigExts {
+			candidates = append(candidates, filepath.Join(filepath.Dir(stem), filepath.Base(stem)+"."+suffix+e))
+		}
+		f := findConfigFile(filepath.Dir(stem), filepath.Base(stem)+"."+suffix)
+		if _, err := os.Stat(f); err != nil || slices.Contains(files, f) {
+			return nil
+		}
+		m, err := readConfigMap(f)
+		if err != nil {
+			return err
+		}
+		mergeConfigMap(merged, m)
+		files = append(files, f)
+		return nil
+	}
+
+	env := strings.TrimSpace(os.Getenv(MainConfigEnvEnvName))
+	if env == "" {
+		env = ConfigEnv
+	}
+	if err = merge(env); err != nil {
+		return nil, nil, nil, err
+	}
+	if hostname, _ := os.Hostname(); hostname != "" {
+		if err = merge(hostname); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if id, ok := nodeIDFromConfigMap(merged); ok {
+		if err = merge(NodeConfigPrefix + strconv.Itoa(id)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	body, err = json.Marshal(merged)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return body, files, candidates, nil
+}
+
+// 读取配置文件为对象
+func readConfigMap(name string) (map[string]any, error) {
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	js, err := ConfigToJSON(name, body)
+	if err != nil {
+		return nil, err
+	}
+	// 数字保留原文, 避免大整数经 float64 丢失精度
+	var m map[string]any
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse %s err: %w", name, err)
+	}
+	if m == nil {
+		m = make(map[string]any)
+	}
+	return m, nil
+}
+
+// 深度合并 src 到 dst: 双方均为对象时递归合并, 否则 src 值替换 dst 值
+func mergeConfigMap(dst, src map[string]any) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				mergeConfigMap(dm, sm)
+				continue
+			}
+		}
+		dst[k] = sv
+	}
+}
+
+// 从已合并配置的 node_conf.node_info_file 节点信息文件中读取 node_id
+func nodeIDFromConfigMap(m map[string]any) (int, bool) {
+	nodeConf, _ := m["node_conf"].(map[string]any)
+	f, _ := nodeConf["node_info_file"].(string)
+	if f == "" {
+		return 0, false
+	}
+	body, err := os.ReadFile(f)
+	if err != nil {
+		return 0, false
+	}
+	var nInfo nodeInfoFileData
+	if err := UnmarshalConfig(f, body, &nInfo); err != nil || nInfo.NodeID == 0 {
+		return 0, false
+	}
+	return nInfo.NodeID, true
+}
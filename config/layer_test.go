@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// TestMergeConfigFiles 验证分层合并顺序 (基础 > 环境 > 主机 > 节点), 对象深度合并, 数组整体替换.
+func TestMergeConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	hostname, err := os.Hostname()
+	assert.Nil(t, err)
+	nodeInfoFile := filepath.Join(dir, "node_info.json")
+
+	write := func(name, body string) string {
+		f := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(f, []byte(body), 0o600))
+		return f
+	}
+	base := write("app.json", `{
+  "sys_conf": {"env_files": ["a.env", "b.env"], "req_timeout": "3s"},
+  "log_conf": {"level": 2, "file": "app.log"},
+  "web_conf": {"server_addr": ":80", "groups": {"api": {"server_addr": ":81", "name": "api"}}}
+}`)
+	env := write("app.dc1.yaml", `
+sys_conf:
+  env_files: [c.env]
+web_conf:
+  groups:
+    api:
+      server_addr: ":8081"
+node_conf:
+  node_info_file: `+nodeInfoFile+`
+`)
+	host := write("app."+hostname+".toml", `
+[log_conf]
+level = 1
+`)
+	write("node_info.json", `{"node_id": 7}`)
+	node := write("app.node-7.json", `{"log_conf": {"file": "node.log"}}`)
+	write("app.dc2.json", `{"log_conf": {"level": 3}}`)
+
+	t.Setenv(MainConfigEnvEnvName, "dc1")
+	body, files, _, err := mergeConfigFiles(base)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{base, env, host, node}, files)
+
+	cfg := new(MainConf)
+	assert.Nil(t, json.Unmarshal(body, cfg))
+	assert.Equal(t, []string{"c.env"}, cfg.SYSConf.EnvFiles)
+	assert.Equal(t, "3s", cfg.SYSConf.ReqTimeout)
+	assert.Equal(t, 1, cfg.LogConf.Level)
+	assert.Equal(t, "node.log", cfg.LogConf.File)
+	assert.Equal(t, ":80", cfg.WebConf.ServerAddr)
+	assert.Equal(t, ":8081", cfg.WebConf.Groups["api"].ServerAddr)
+	assert.Equal(t, "api", cfg.WebConf.Groups["api"].Name)
+
+	// 无环境层时, 节点信息文件路径也不存在, 仅合并基础和主机层
+	t.Setenv(MainConfigEnvEnvName, "")
+	_, files, candidates, err := mergeConfigFiles(base)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{base, host}, files)
+	assert.Equal(t, len(ConfigExts), len(candidates))
+	assert.Equal(t, filepath.Join(dir, "app."+hostname+".json"), candidates[0])
+
+	// 启动后创建的分层文件也在候选列表中
+	t.Setenv(MainConfigEnvEnvName, "dc9")
+	_, _, candidates, err = mergeConfigFiles(base)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "app.dc9.json"), candidates[0])
+	assert.Equal(t, filepath.Join(dir, "app.dc9.toml"), candidates[len(ConfigExts)-1])
+
+	// 任一层格式错误时返回错误
+	write("app.dc3.yaml", "log_conf: [1")
+	t.Setenv(MainConfigEnvEnvName, "dc3")
+	_, _, _, err = mergeConfigFiles(base)
+	assert.NotNil(t, err)
+}
+
+func TestMergeConfigFilesLargeNumber(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "app.json")
+	assert.Nil(t, os.WriteFile(base, []byte(`{"node_conf": {"node_info": {"node_id": 9007199254740993}}, "log_conf": {"level": 1}}`), 0o600))
+	t.Setenv(MainConfigEnvEnvName, "")
+
+	body, _, _, err := mergeConfigFiles(base)
+	assert.Nil(t, err)
+	assert.Contains(t, "9007199254740993", string(body))
+}
@@ -33,7 +33,7 @@ var (
 
 type NodeConf struct {
 	NodeInfoFile string `json:"node_info_file"`
-	IPAPI        string `json:"ip_api"`
+	IPAPI        string `json:"ip_api" validate:"urls"`
 	NodeInfo     NodeInfo
 }
 
@@ -87,7 +87,7 @@ func parseNodeInfoConfig(cfg *MainConf) {
 
 	// 节点 IP 为空时, 以出口 IP 作为节点 IP
 	ip := cfg.NodeConf.NodeInfo.NodeIP
-	if ip == "" && cfg.NodeConf.IPAPI != "" {
+	if ip == "" && cfg.NodeConf.IPAPI != "" && !DryRun {
 		ip = GetNodeIPFromAPIs(cfg.NodeConf.IPAPI)
 		if ip == "" {
 			// 节点出口 IP 没有获取成功时, 使用已保存的 NodeIP
@@ -117,7 +117,7 @@ func parseNodeInfoJson(cfg *MainConf) {
 		return
 	}
 	var nInfo nodeInfoFileData
-	if err := json.Unmarshal(body, &nInfo); err != nil {
+	if err := UnmarshalConfig(NodeInfoFile, body, &nInfo); err != nil {
 		return
 	}
 	cfg.NodeConf.NodeInfo.NodeID = nInfo.NodeID
@@ -153,7 +153,7 @@ func parseNodeInfoJsonBackup(cfg *MainConf) {
 }
 
 func saveNodeInfoBackup(info NodeInfo) {
-	if info.NodeIP != net.IPv4zero.String() {
+	if !DryRun && info.NodeIP != net.IPv4zero.String() {
 		_ = os.WriteFile(NodeInfoBackupFile, json.MustJSON(info), 0o600)
 	}
 }
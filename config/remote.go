@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -18,6 +19,8 @@ type RespDataSource struct {
 	OK   int              `json:"ok"`
 	Msg  string           `json:"msg"`
 	Data []map[string]any `json:"data"`
+	// Signature 配置内容签名 (开启签名校验且 verify.field 非空时), 签名内容为拼接后的配置文本
+	Signature string `json:"signature"`
 }
 
 type DataSourceArgs struct {
@@ -32,24 +35,46 @@ func GetDataSource(args any) error {
 	if !ok {
 		return fmt.Errorf("invalid data source configuration: %T", args)
 	}
-
-	// 新旧文件内容不同时重写文件
-	shouldUpdateFile := func(contentBytes []byte) (bool, error) {
-		md5Old := xhash.MustMD5Sum(params.Conf.Path)
-		md5New := xhash.MD5BytesHex(contentBytes)
-		return md5New != md5Old, nil
-	}
-
-	return GetDataSourceWithCheck(params, shouldUpdateFile)
+	return GetDataSourceWithCheck(params, ShouldUpdateFile(params.Conf.Path))
 }
 
 // GetDataSourceWithCheck 获取数据源并根据检查结果更新本地文件
+// 开启签名校验时, 签名取自响应头 verify.header, 或 verify.field 非空时取自响应 signature 字段
 func GetDataSourceWithCheck(params DataSourceArgs, shouldUpdate func([]byte) (bool, error)) error {
-	body, err := GetDataSourceBody(params)
+	body, sig, err := getDataSourceBody(params)
 	if err != nil {
 		return fmt.Errorf("failed to fetch data source content: %w", err)
 	}
-	contentBytes := utils.S2B(body)
+	if params.Conf.Verify.Method != "" {
+		if err := verifyPayload(params.Conf, utils.S2B(body), sig); err != nil {
+			return err
+		}
+	}
+	return WriteDataSource(params.Conf, utils.S2B(body), shouldUpdate)
+}
+
+// ShouldUpdateFile 新旧文件内容不同时重写文件
+func ShouldUpdateFile(path string) func([]byte) (bool, error) {
+	return func(contentBytes []byte) (bool, error) {
+		md5Old := xhash.MustMD5Sum(path)
+		md5New := xhash.MD5BytesHex(contentBytes)
+		return md5New != md5Old, nil
+	}
+}
+
+// WriteDataSource 校验数据源内容并根据检查结果更新本地文件 (cfg.Path), 各远端配置获取方法共用
+// 内容格式校验失败时返回 ErrDataSourceVerify 包装的错误, 不覆盖本地文件
+func WriteDataSource(cfg FilesConf, contentBytes []byte, shouldUpdate func([]byte) (bool, error)) error {
+	// 配置文件 (json/yaml/toml) 内容无法解析时不覆盖本地文件
+	if IsConfigExt(filepath.Ext(cfg.Path)) {
+		var v any
+		if err := UnmarshalConfig(cfg.Path, contentBytes, &v); err != nil {
+			return verifyFailed(cfg, fmt.Errorf("invalid data source content: %w", err))
+		}
+	}
+	if err := CheckDataSourceFormat(cfg.Verify.Format, contentBytes); err != nil {
+		return verifyFailed(cfg, err)
+	}
 
 	if shouldUpdate != nil {
 		update, err := shouldUpdate(contentBytes)
@@ -62,14 +87,23 @@ func GetDataSourceWithCheck(params DataSourceArgs, shouldUpdate func([]byte) (bo
 		}
 	}
 
-	if err = os.WriteFile(params.Conf.Path, contentBytes, 0o600); err != nil {
+	// 保留历史版本: 覆盖前的本地内容 (如有未记录的本地修改) 和远端新内容, 记录失败不影响更新
+	_, _ = History.Record(cfg.Path, HistorySourceLocal)
+	if err := os.WriteFile(cfg.Path, contentBytes, 0o600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	_, _ = History.RecordContent(cfg.Path, contentBytes, HistorySourceRemote+":"+cfg.Method)
 	return nil
 }
 
 // GetDataSourceBody 获取数据源内容
 func GetDataSourceBody(params DataSourceArgs) (string, error) {
+	body, _, err := getDataSourceBody(params)
+	return body, err
+}
+
+// 获取数据源内容及签名
+func getDataSourceBody(params DataSourceArgs) (string, string, error) {
 	// Token: md5(timestamp + auth_key)
 	timestamp := strconv.FormatInt(params.Time.Unix(), 10)
 	token := xhash.MD5Hex(timestamp + params.Conf.SecretValue)
@@ -78,11 +112,11 @@ func GetDataSourceBody(params DataSourceArgs) (string, error) {
 	var res RespDataSource
 	resp, err := req.SetSuccessResult(&res).SetErrorResult(&res).Get(params.Conf.API + token + "&time=" + timestamp)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if res.OK != 1 || !resp.IsSuccessState() {
-		return "", fmt.Errorf("data source request failed: [%d] %s", resp.StatusCode, res.Msg)
+		return "", "", fmt.Errorf("data source request failed: [%d] %s", resp.StatusCode, res.Msg)
 	}
 
 	// 获取所有配置项数据
@@ -96,7 +130,12 @@ func GetDataSourceBody(params DataSourceArgs) (string, error) {
 
 	body := strings.TrimSpace(builder.String())
 	if body == "" {
-		return "", errors.New("data source result is empty")
+		return "", "", errors.New("data source result is empty")
+	}
+
+	sig := res.Signature
+	if params.Conf.Verify.Field == "" {
+		sig = resp.Header.Get(defaultString(params.Conf.Verify.Header, DefaultVerifyHeader))
 	}
-	return body, nil
+	return body, sig, nil
 }
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// RenderConfig 当前生效的配置 (含应用自定义配置节), 由密钥引用解析得到的值脱敏, 用于排查和展示
+// 敏感配置项 (secret 标签) 均为 json:"-", 不会输出
+func RenderConfig() ([]byte, error) {
+	cfg := Config()
+	if cfg == nil {
+		return nil, errors.New("config not loaded")
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(json.MustJSON(cfg), &m); err != nil {
+		return nil, err
+	}
+	for name, v := range loadSections() {
+		if reflect.ValueOf(v).IsNil() {
+			continue
+		}
+		var sv any
+		if err := json.Unmarshal(json.MustJSON(v), &sv); err != nil {
+			return nil, err
+		}
+		m[name] = sv
+	}
+	for _, p := range secretRefPaths {
+		maskConfigPath(m, strings.Split(p, "."))
+	}
+	return json.MustJSONUnEscapeIndent(m), nil
+}
+
+// 将 JSON 对象中指定路径的值替换为 RedactedValue
+func maskConfigPath(v any, segs []string) any {
+	if len(segs) == 0 {
+		return RedactedValue
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		if old, ok := x[segs[0]]; ok {
+			x[segs[0]] = maskConfigPath(old, segs[1:])
+		}
+	case []any:
+		if i, err := strconv.Atoi(segs[0]); err == nil && i >= 0 && i < len(x) {
+			x[i] = maskConfigPath(x[i], segs[1:])
+		}
+	}
+	return v
+}
+
+// DiffConfigFiles 对比两个配置文件 (json/yaml/toml, 格式可不同) 的内容, 不做合并和解析
+// 返回所有差异配置项, 按路径排序, 新增或删除的配置项旧值或新值为 nil
+func DiffConfigFiles(oldFile, newFile string) (*ConfigDiff, error) {
+	o, err := readConfigMap(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	n, err := readConfigMap(newFile)
+	if err != nil {
+		return nil, err
+	}
+	d := new(ConfigDiff)
+	diffConfigMap(o, n, "", &d.Changes)
+	return d, nil
+}
+
+func diffConfigMap(o, n map[string]any, path string, changes *[]Change) {
+	keys := make([]string, 0, len(o)+len(n))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	for k := range n {
+		if _, ok := o[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ov, nv := o[k], n[k]
+		om, ok1 := ov.(map[string]any)
+		nm, ok2 := nv.(map[string]any)
+		if ok1 && ok2 {
+			diffConfigMap(om, nm, joinPath(path, k), changes)
+			continue
+		}
+		if !reflect.DeepEqual(ov, nv) {
+			*changes = append(*changes, Change{Path: joinPath(path, k), Old: ov, New: nv})
+		}
+	}
+}
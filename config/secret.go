@@ -0,0 +1,316 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imroc/req/v3"
+)
+
+// SecretRefScheme 密钥引用前缀, 配置项值或密钥环境变量值为 secret://{provider}/{path} 时, 由对应的 SecretProvider 获取
+//
+//	"post_api": "secret://vault/app/log#post_api"
+//	WEB_SIGN_KEY=secret://keyring/web_sign_key
+const SecretRefScheme = "secret://"
+
+var (
+	// VaultAddrEnvName Vault 服务地址环境变量名, 如: http://127.0.0.1:8200
+	VaultAddrEnvName = "VAULT_ADDR"
+	// VaultTokenEnvName Vault 访问令牌环境变量名, 值使用基础密钥加密
+	VaultTokenEnvName = "VAULT_TOKEN"
+	// VaultMount Vault KV v2 引擎挂载路径
+	VaultMount = "secret"
+	// VaultDefaultField 密钥引用未指定字段 (#field) 时读取的字段名
+	VaultDefaultField = "value"
+
+	// SecretKeyringFile 本地密钥环文件 (json/yaml/toml), 键 => 使用基础密钥加密的值, 默认为 etc/secret.keyring.json
+	SecretKeyringFile string
+
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"env":     EnvSecretProvider{},
+		"keyring": KeyringSecretProvider{},
+		"vault":   VaultSecretProvider{},
+	}
+
+	// 最近一次加载时由密钥引用解析得到值的配置项路径, 差异报告中脱敏
+	secretRefPaths []string
+)
+
+// SecretProvider 密钥提供者, 可通过 RegisterSecretProvider 注册自定义实现或替换内置实现
+type SecretProvider interface {
+	// GetSecret 获取密钥明文, path 为密钥引用中 provider 之后的部分
+	GetSecret(path string) (string, error)
+}
+
+// SecretProviderFunc 函数适配为 SecretProvider
+type SecretProviderFunc func(path string) (string, error)
+
+func (f SecretProviderFunc) GetSecret(path string) (string, error) {
+	return f(path)
+}
+
+// RegisterSecretProvider 注册密钥提供者, 同名时替换
+func RegisterSecretProvider(name string, p SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[name] = p
+}
+
+// GetSecretProvider 获取已注册的密钥提供者
+func GetSecretProvider(name string) (SecretProvider, bool) {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	p, ok := secretProviders[name]
+	return p, ok
+}
+
+// IsSecretRef 是否为密钥引用
+func IsSecretRef(s string) bool {
+	return strings.HasPrefix(s, SecretRefScheme)
+}
+
+// ResolveSecret 解析密钥引用, 非密钥引用原样返回
+func ResolveSecret(ref string) (string, error) {
+	if !IsSecretRef(ref) {
+		return ref, nil
+	}
+	name, path, _ := strings.Cut(strings.TrimPrefix(ref, SecretRefScheme), "/")
+	p, ok := GetSecretProvider(name)
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider: %s", name)
+	}
+	if path == "" {
+		return "", fmt.Errorf("invalid secret ref: %s", ref)
+	}
+	value, err := p.GetSecret(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", ref, err)
+	}
+	return value, nil
+}
+
+// GetenvSecret 获取密钥环境变量的明文值
+// 环境变量值为密钥引用时由 SecretProvider 获取, 否则使用 secret 解密, secret 为基础密钥时支持密钥轮换
+func GetenvSecret(key, secret string) (string, error) {
+	if v := os.Getenv(key); IsSecretRef(v) {
+		return ResolveSecret(v)
+	}
+	if secret == BaseSecretValue {
+		return GetenvDecrypt(key), nil
+	}
+	v, _ := DecryptSecretWith(os.Getenv(key), secret)
+	return v, nil
+}
+
+// EnvSecretProvider 加密环境变量, path 为环境变量名, 值使用基础密钥加密
+//
+//	secret://env/REDIS_AUTH
+type EnvSecretProvider struct{}
+
+func (EnvSecretProvider) GetSecret(path string) (string, error) {
+	v := GetenvDecrypt(path)
+	if v == "" {
+		return "", fmt.Errorf("%s cannot be empty", path)
+	}
+	return v, nil
+}
+
+// KeyringSecretProvider 本地加密密钥环文件, path 为键名, 值使用基础密钥加密
+// File 为空时使用 SecretKeyringFile, 每次获取时读取文件, 热加载时随之更新
+//
+//	secret://keyring/redis_auth
+type KeyringSecretProvider struct {
+	File string
+}
+
+func (p KeyringSecretProvider) GetSecret(path string) (string, error) {
+	f := p.File
+	if f == "" {
+		f = SecretKeyringFile
+	}
+	body, err := os.ReadFile(f)
+	if err != nil {
+		return "", err
+	}
+	var keyring map[string]string
+	if err := UnmarshalConfig(f, body, &keyring); err != nil {
+		return "", fmt.Errorf("parse keyring %s err: %w", f, err)
+	}
+	encrypted, ok := keyring[path]
+	if !ok {
+		return "", fmt.Errorf("keyring %s: %s not found", f, path)
+	}
+	v := DecryptSecret("keyring:"+path, encrypted)
+	if v == "" {
+		return "", fmt.Errorf("keyring %s: %s cannot be empty", f, path)
+	}
+	return v, nil
+}
+
+// VaultSecretProvider HashiCorp Vault 兼容的 KV v2 HTTP 接口, path 为 {secret_path}#{field}
+// Addr/Token/Mount 为空时分别使用 VAULT_ADDR, VAULT_TOKEN (基础密钥加密) 环境变量和 VaultMount
+// Timeout 为空时使用请求超时配置 (req_timeout), 避免 Vault 无响应时阻塞配置加载
+//
+//	secret://vault/app/db#password => GET {addr}/v1/secret/data/app/db, 取 data.data.password
+type VaultSecretProvider struct {
+	Addr    string
+	Token   string
+	Mount   string
+	Timeout time.Duration
+}
+
+func (p VaultSecretProvider) GetSecret(path string) (string, error) {
+	addr, token, mount := p.Addr, p.Token, p.Mount
+	if addr == "" {
+		addr = os.Getenv(VaultAddrEnvName)
+	}
+	if addr == "" {
+		return "", fmt.Errorf("%s cannot be empty", VaultAddrEnvName)
+	}
+	if token == "" {
+		token = GetenvDecrypt(VaultTokenEnvName)
+	}
+	if mount == "" {
+		mount = VaultMount
+	}
+	path, field, _ := strings.Cut(path, "#")
+	if field == "" {
+		field = VaultDefaultField
+	}
+
+	var res struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = ReqTimeoutDuration
+		if cfg := Config(); cfg != nil && cfg.SYSConf.ReqTimeoutDuration > 0 {
+			timeout = cfg.SYSConf.ReqTimeoutDuration
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	u := strings.TrimRight(addr, "/") + "/v1/" + url.PathEscape(strings.Trim(mount, "/")) + "/data/" + strings.Trim(path, "/")
+	resp, err := req.R().SetContext(ctx).SetHeader("X-Vault-Token", token).
+		SetSuccessResult(&res).SetErrorResult(&res).Get(u)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsSuccessState() {
+		return "", fmt.Errorf("vault request failed: [%d] %s", resp.StatusCode, strings.Join(res.Errors, "; "))
+	}
+	v, ok := res.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("vault %s: field %s not found", path, field)
+	}
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("vault %s: field %s must be a non-empty string", path, field)
+	}
+	return s, nil
+}
+
+// 解析配置中所有密钥引用 (含 map/slice 中的字符串), 不含 json 标签的字段跳过
+// paths 记录被解析的配置项路径, 解析失败时记录为 FieldError
+func resolveSecretRefs(v reflect.Value, path string, paths *[]string, errs *[]*FieldError) {
+	switch v.Kind() {
+	case reflect.String:
+		if ref := v.String(); IsSecretRef(ref) && v.CanSet() {
+			s, err := ResolveSecret(ref)
+			if err != nil {
+				*errs = append(*errs, &FieldError{Path: path, Value: ref, Reason: err.Error()})
+				return
+			}
+			v.SetString(s)
+			*paths = append(*paths, path)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if name := jsonFieldName(t.Field(i)); name != "" && v.Field(i).CanSet() {
+				resolveSecretRefs(v.Field(i), joinPath(path, name), paths, errs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			resolveSecretRefs(v.Index(i), joinPath(path, strconv.Itoa(i)), paths, errs)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			n := len(*paths)
+			resolveSecretRefs(elem, joinPath(path, iter.Key().String()), paths, errs)
+			if len(*paths) > n {
+				v.SetMapIndex(iter.Key(), elem)
+			}
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			resolveSecretRefs(v.Elem(), path, paths, errs)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		if elem.Kind() == reflect.String {
+			s := reflect.New(elem.Type()).Elem()
+			s.Set(elem)
+			n := len(*paths)
+			resolveSecretRefs(s, path, paths, errs)
+			if len(*paths) > n {
+				v.Set(s)
+			}
+			return
+		}
+		resolveSecretRefs(elem, path, paths, errs)
+	default:
+	}
+}
+
+// 解析主配置中的密钥引用
+func applySecretRefs(cfg *MainConf) ([]string, error) {
+	var paths []string
+	var errs []*FieldError
+	resolveSecretRefs(reflect.ValueOf(cfg).Elem(), "", &paths, &errs)
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	return paths, nil
+}
+
+// 脱敏由密钥引用解析得到的配置项变化
+func redactSecretChanges(changes []Change, paths []string) {
+	for i, c := range changes {
+		if slices.ContainsFunc(paths, func(p string) bool {
+			return c.Path == p || strings.HasPrefix(p, c.Path+".") || strings.HasPrefix(c.Path, p+".")
+		}) {
+			changes[i].Old, changes[i].New = redactAny(c.Old), redactAny(c.New)
+		}
+	}
+}
+
+func redactAny(v any) any {
+	if v == nil {
+		return nil
+	}
+	return redact(v)
+}
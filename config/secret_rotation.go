@@ -0,0 +1,153 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fufuok/utils/xcrypto"
+)
+
+// 带校验的加密值: {base58 密文}.{HMAC-SHA256 前 16 字节 hex}
+// 密钥轮换期间由校验结果确定加密所用的密钥, 不依赖解密结果的内容
+const (
+	secretMACSeparator = '.'
+	secretMACSize      = 16
+)
+
+var (
+	secondarySecretKeysMu sync.Mutex
+	// 仍使用次基础密钥 (旧密钥) 加密的值名称
+	secondarySecretKeys = make(map[string]struct{})
+)
+
+// GetenvDecrypt 解密使用基础密钥加密的环境变量, 密钥轮换期间兼容次基础密钥, 替代:
+//
+//	xcrypto.GetenvDecrypt(key, config.Config().SYSConf.BaseSecretValue)
+//
+// 注意: EncryptSecret (加密工具) 生成的带校验的值, 不能再使用 xcrypto.GetenvDecrypt 解密
+func GetenvDecrypt(key string) string {
+	return DecryptSecret(key, os.Getenv(key))
+}
+
+// EncryptSecret 使用指定密钥加密 (同 xcrypto.Encrypt), 并附加校验
+// 带校验的值由 DecryptSecret 按校验结果选择基础密钥或次基础密钥解密, secret 为空时返回原值
+func EncryptSecret(value, secret string) string {
+	if secret == "" {
+		return value
+	}
+	v := xcrypto.Encrypt(value, secret)
+	return v + string(secretMACSeparator) + secretMAC(v, secret)
+}
+
+// DecryptSecret 解密使用基础密钥或次基础密钥加密的值
+// 带校验的值 (EncryptSecret) 按校验结果选择密钥, 都不匹配时返回空, 次基础密钥解密成功时记录 name,
+// 可通过 SecondarySecretKeys() 获取待重新加密的值.
+// 无校验的旧格式值 (xcrypto.Encrypt) 解密结果不是可打印文本时视为密钥不匹配, 依次尝试基础密钥和次基础密钥,
+// 都不匹配时返回空; 该判断对短值不完全可靠, 轮换前应使用 env-reencrypt 转换为带校验的格式.
+func DecryptSecret(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	var (
+		v  string
+		ok bool
+	)
+	if enc, mac, tagged := splitSecretMAC(value); tagged {
+		if v, ok = decryptWith(enc, mac, BaseSecretValue); ok {
+			return v
+		}
+		v, ok = decryptWith(enc, mac, BaseSecretSecondaryValue)
+	} else {
+		if v = decryptLegacy(value, BaseSecretValue); v != "" {
+			return v
+		}
+		if BaseSecretSecondaryValue != "" {
+			v = decryptLegacy(value, BaseSecretSecondaryValue)
+			ok = v != ""
+		}
+	}
+	if ok {
+		secondarySecretKeysMu.Lock()
+		secondarySecretKeys[name] = struct{}{}
+		secondarySecretKeysMu.Unlock()
+	}
+	return v
+}
+
+// SecondarySecretKeys 最近一次加载配置以来, 仍使用次基础密钥 (旧密钥) 加密的值名称 (环境变量名或 keyring:键名)
+// 列表为空后即可移除 BASE_SECRET_KEY_SECONDARY, 完成密钥轮换
+func SecondarySecretKeys() []string {
+	secondarySecretKeysMu.Lock()
+	defer secondarySecretKeysMu.Unlock()
+	keys := make([]string, 0, len(secondarySecretKeys))
+	for k := range secondarySecretKeys {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// DecryptSecretWith 使用指定密钥解密, 带校验的值校验失败时返回 false, 旧格式值直接解密
+func DecryptSecretWith(value, secret string) (string, bool) {
+	if enc, mac, ok := splitSecretMAC(value); ok {
+		return decryptWith(enc, mac, secret)
+	}
+	return xcrypto.Decrypt(value, secret), true
+}
+
+// 校验通过后使用指定密钥解密, 密钥不匹配或密文被修改时返回 false
+func decryptWith(enc, mac, secret string) (string, bool) {
+	if secret == "" || !hmac.Equal([]byte(mac), []byte(secretMAC(enc, secret))) {
+		return "", false
+	}
+	return xcrypto.Decrypt(enc, secret), true
+}
+
+// 拆分带校验的加密值, base58 密文中不含分隔符
+func splitSecretMAC(value string) (enc, mac string, ok bool) {
+	i := strings.LastIndexByte(value, secretMACSeparator)
+	if i <= 0 || len(value)-i-1 != hex.EncodedLen(secretMACSize) {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(value[i+1:]); err != nil {
+		return "", "", false
+	}
+	return value[:i], value[i+1:], true
+}
+
+func secretMAC(enc, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(enc))
+	return hex.EncodeToString(h.Sum(nil)[:secretMACSize])
+}
+
+// 旧格式值 (无校验) 使用指定密钥解密, 结果不是可打印文本时视为明文或密钥不匹配, 返回空
+// 加密使用零填充, 密钥错误时解密不会报错而是得到乱码
+func decryptLegacy(value, secret string) string {
+	if secret == "" {
+		return ""
+	}
+	v := xcrypto.Decrypt(value, secret)
+	if !utf8.ValidString(v) {
+		return ""
+	}
+	for _, r := range v {
+		if unicode.IsControl(r) {
+			return ""
+		}
+	}
+	return v
+}
+
+func resetSecondarySecretKeys() {
+	secondarySecretKeysMu.Lock()
+	clear(secondarySecretKeys)
+	secondarySecretKeysMu.Unlock()
+}
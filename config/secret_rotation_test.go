@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/fufuok/utils/xcrypto"
+)
+
+func TestDecryptSecretRotation(t *testing.T) {
+	oldBase, oldSecondary := BaseSecretValue, BaseSecretSecondaryValue
+	defer func() {
+		BaseSecretValue, BaseSecretSecondaryValue = oldBase, oldSecondary
+		resetSecondarySecretKeys()
+	}()
+	BaseSecretValue, BaseSecretSecondaryValue = "new~~777", "old~~666"
+	resetSecondarySecretKeys()
+
+	t.Setenv("TEST_ROTATE_NEW", EncryptSecret("value-new", "new~~777"))
+	t.Setenv("TEST_ROTATE_OLD", EncryptSecret("value-old-0123456789", "old~~666"))
+	// 含换行和制表符的值按校验结果选择密钥
+	t.Setenv("TEST_ROTATE_MULTILINE", EncryptSecret("line1\n\tline2", "new~~777"))
+	t.Setenv("TEST_ROTATE_OTHER", EncryptSecret("other", "other~~888"))
+	// 旧格式值按解密结果是否为可打印文本选择密钥
+	t.Setenv("TEST_ROTATE_LEGACY", xcrypto.Encrypt("legacy", "new~~777"))
+	t.Setenv("TEST_ROTATE_LEGACY_OLD", xcrypto.Encrypt("legacy-old-0123456789", "old~~666"))
+	assert.Equal(t, "value-new", GetenvDecrypt("TEST_ROTATE_NEW"))
+	assert.Equal(t, "value-old-0123456789", GetenvDecrypt("TEST_ROTATE_OLD"))
+	assert.Equal(t, "line1\n\tline2", GetenvDecrypt("TEST_ROTATE_MULTILINE"))
+	assert.Equal(t, "", GetenvDecrypt("TEST_ROTATE_OTHER"))
+	assert.Equal(t, "legacy", GetenvDecrypt("TEST_ROTATE_LEGACY"))
+	assert.Equal(t, "legacy-old-0123456789", GetenvDecrypt("TEST_ROTATE_LEGACY_OLD"))
+	assert.Equal(t, "", GetenvDecrypt("TEST_ROTATE_NONE"))
+	assert.Equal(t, []string{"TEST_ROTATE_LEGACY_OLD", "TEST_ROTATE_OLD"}, SecondarySecretKeys())
+
+	// 密文被修改时校验失败
+	tampered := []byte(EncryptSecret("value-new", "new~~777"))
+	tampered[0] ^= 1
+	assert.Equal(t, "", DecryptSecret("tampered", string(tampered)))
+
+	// 无次基础密钥时保持原解密行为
+	BaseSecretSecondaryValue = ""
+	resetSecondarySecretKeys()
+	assert.Equal(t, "value-new", GetenvDecrypt("TEST_ROTATE_NEW"))
+	assert.Equal(t, "", GetenvDecrypt("TEST_ROTATE_OLD"))
+	assert.Equal(t, "", GetenvDecrypt("TEST_ROTATE_LEGACY_OLD"))
+	assert.Equal(t, 0, len(SecondarySecretKeys()))
+}
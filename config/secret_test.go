@@ -0,0 +1,145 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/fufuok/utils/xcrypto"
+)
+
+func TestSecretProviders(t *testing.T) {
+	oldBase := BaseSecretValue
+	defer func() {
+		BaseSecretValue = oldBase
+	}()
+	BaseSecretValue = "Tester"
+
+	// 加密环境变量
+	t.Setenv("TEST_SECRET_ENV", xcrypto.Encrypt("env-value", BaseSecretValue))
+	v, err := ResolveSecret("secret://env/TEST_SECRET_ENV")
+	assert.Nil(t, err)
+	assert.Equal(t, "env-value", v)
+
+	// 本地密钥环文件
+	keyring := filepath.Join(t.TempDir(), "keyring.yaml")
+	assert.Nil(t, os.WriteFile(keyring, []byte("db: "+xcrypto.Encrypt("keyring-value", BaseSecretValue)), 0o600))
+	RegisterSecretProvider("test_keyring", KeyringSecretProvider{File: keyring})
+	v, err = ResolveSecret("secret://test_keyring/db")
+	assert.Nil(t, err)
+	assert.Equal(t, "keyring-value", v)
+	_, err = ResolveSecret("secret://test_keyring/none")
+	assert.NotNil(t, err)
+
+	// Vault KV v2 接口
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/app/db" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"password":"vault-value","value":"default","port":3306}}}`))
+	}))
+	defer ts.Close()
+	t.Setenv(VaultAddrEnvName, ts.URL)
+	t.Setenv(VaultTokenEnvName, xcrypto.Encrypt("vault-token", BaseSecretValue))
+	RegisterSecretProvider("test_vault", VaultSecretProvider{Mount: "kv"})
+	v, err = ResolveSecret("secret://test_vault/app/db#password")
+	assert.Nil(t, err)
+	assert.Equal(t, "vault-value", v)
+	v, err = ResolveSecret("secret://test_vault/app/db")
+	assert.Nil(t, err)
+	assert.Equal(t, "default", v)
+	_, err = ResolveSecret("secret://test_vault/app/none")
+	assert.Contains(t, "404", err.Error())
+	_, err = ResolveSecret("secret://test_vault/app/db#user")
+	assert.Contains(t, "field user not found", err.Error())
+	_, err = ResolveSecret("secret://test_vault/app/db#port")
+	assert.Contains(t, "field port must be a non-empty string", err.Error())
+	t.Setenv(VaultTokenEnvName, "")
+	_, err = ResolveSecret("secret://test_vault/app/db")
+	assert.Contains(t, "permission denied", err.Error())
+
+	// 非引用原样返回, 未知提供者报错
+	v, err = ResolveSecret("plain")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", v)
+	_, err = ResolveSecret("secret://unknown/x")
+	assert.NotNil(t, err)
+
+	// 密钥环境变量兼容原加密值
+	t.Setenv("TEST_SECRET_REF", "secret://env/TEST_SECRET_ENV")
+	v, err = GetenvSecret("TEST_SECRET_REF", BaseSecretValue)
+	assert.Nil(t, err)
+	assert.Equal(t, "env-value", v)
+	v, err = GetenvSecret("TEST_SECRET_ENV", BaseSecretValue)
+	assert.Nil(t, err)
+	assert.Equal(t, "env-value", v)
+}
+
+func TestVaultSecretProviderTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	p := VaultSecretProvider{Addr: ts.URL, Token: "vault-token", Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	_, err := p.GetSecret("app/db")
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestApplySecretRefs(t *testing.T) {
+	values := map[string]string{"a": "value-a", "b": "value-b"}
+	RegisterSecretProvider("test_map", SecretProviderFunc(func(path string) (string, error) {
+		return values[path], nil
+	}))
+
+	cfg := &MainConf{
+		LogConf: LogConf{PostAPI: "secret://test_map/a"},
+		WebConf: WebConf{
+			TrustedProxies: []string{"127.0.0.1", "secret://test_map/b"},
+			Groups:         map[string]WebConf{"api": {ProxyHeader: "secret://test_map/a"}},
+		},
+	}
+	paths, err := applySecretRefs(cfg)
+	assert.Nil(t, err)
+	slices.Sort(paths)
+	assert.Equal(t, []string{"log_conf.post_api", "web_conf.groups.api.proxy_header", "web_conf.trusted_proxies.1"}, paths)
+	assert.Equal(t, "value-a", cfg.LogConf.PostAPI)
+	assert.Equal(t, []string{"127.0.0.1", "value-b"}, cfg.WebConf.TrustedProxies)
+	assert.Equal(t, "value-a", cfg.WebConf.Groups["api"].ProxyHeader)
+
+	_, err = applySecretRefs(&MainConf{LogConf: LogConf{File: "secret://unknown/x"}})
+	ve, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"log_conf.file"}, ve.Paths())
+
+	// 差异报告中脱敏
+	changes := []Change{
+		{Path: "log_conf.post_api", Old: "old", New: "value-a"},
+		{Path: "web_conf.trusted_proxies", Old: []string{}, New: []string{"value-b"}},
+		{Path: "log_conf.file", Old: "a.log", New: "b.log"},
+	}
+	redactSecretChanges(changes, paths)
+	assert.Equal(t, RedactedValue, changes[0].New)
+	assert.Equal(t, RedactedValue, changes[1].New)
+	assert.Equal(t, "b.log", changes[2].New)
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fufuok/pkg/json"
+)
+
+var (
+	sectionsMu sync.RWMutex
+	sections   []section
+
+	// 最近一次成功加载的主配置 JSON 内容 (已合并各层), 用于配置加载后注册的配置节
+	lastConfigBody atomic.Pointer[[]byte]
+)
+
+// Section 应用自定义配置节, 从主配置文件的顶级键解码为 T, 随主配置热加载
+type Section[T any] struct {
+	name     string
+	defaults T
+	validate func(*T) error
+	value    atomic.Pointer[T]
+}
+
+type section interface {
+	sectionName() string
+	decode(raw []byte) (any, []string, error)
+	load() any
+	store(v any)
+	diff(oldVal, newVal any) []Change
+}
+
+// RegisterSection 注册应用自定义配置节, 应在 config.M.Start() 前 (如 init 中) 调用
+//
+//	type BizConf struct {
+//	    Workers int    `json:"workers" validate:"min=1"`
+//	    API     string `json:"api" validate:"url"`
+//	}
+//	var bizConf = config.RegisterSection("biz_conf", BizConf{Workers: 4}, nil)
+//	// bizConf.Get().Workers
+//
+// 解码时以 defaults 为初始值, 配置中未出现的字段保持默认值. 支持 validate 标签约束和 validate 函数校验,
+// 支持 FF_{NAME}__{KEY} 环境变量覆盖. 校验失败时与主配置错误一样阻止本次加载.
+// 配置已加载后注册时立即解码, 失败时 panic. name 重复或与主配置项冲突时 panic.
+func RegisterSection[T any](name string, defaults T, validate func(*T) error) *Section[T] {
+	if name == "" {
+		panic("config: section name cannot be empty")
+	}
+	if _, ok := fieldByJSONName(reflect.ValueOf(MainConf{}), name); ok {
+		panic("config: section name conflicts with main config: " + name)
+	}
+
+	s := &Section[T]{
+		name:     name,
+		defaults: defaults,
+		validate: validate,
+	}
+
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+	for _, item := range sections {
+		if item.sectionName() == name {
+			panic("config: section already registered: " + name)
+		}
+	}
+
+	if body := lastConfigBody.Load(); body != nil {
+		raws, err := splitSections(*body)
+		if err != nil {
+			panic(err)
+		}
+		v, _, err := s.decode(raws[name])
+		if err != nil {
+			panic(err)
+		}
+		s.store(v)
+	}
+	sections = append(sections, s)
+	return s
+}
+
+// Name 配置节名称 (主配置文件顶级键)
+func (s *Section[T]) Name() string {
+	return s.name
+}
+
+// Get 获取当前配置节, 未加载时为默认值. 返回值只读, 勿修改
+func (s *Section[T]) Get() *T {
+	if v := s.value.Load(); v != nil {
+		return v
+	}
+	v := s.defaults
+	return &v
+}
+
+func (s *Section[T]) sectionName() string {
+	return s.name
+}
+
+func (s *Section[T]) decode(raw []byte) (any, []string, error) {
+	// 深拷贝默认值, 避免解码和环境变量覆盖修改默认值中的 map/slice
+	var v T
+	cloneValue(reflect.ValueOf(&v).Elem(), reflect.ValueOf(&s.defaults).Elem())
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, nil, fmt.Errorf("parse %s err: %w", s.name, err)
+		}
+	}
+
+	// 环境变量覆盖: FF_{NAME}__{KEY}
+	rv := reflect.ValueOf(&v).Elem()
+	var errs []*FieldError
+	for _, k := range envOverrideKeys() {
+		segs := envOverridePath(k)
+		if !strings.EqualFold(segs[0], s.name) {
+			continue
+		}
+		segs[0] = s.name
+		raw := os.Getenv(k)
+		if err := setConfigPath(rv, segs[1:], raw); err != nil {
+			errs = append(errs, &FieldError{Path: strings.Join(segs, "."), Value: raw, Reason: "env " + k + ": " + err.Error()})
+		}
+	}
+	var secretPaths []string
+	resolveSecretRefs(rv, s.name, &secretPaths, &errs)
+	if rv.Kind() == reflect.Struct {
+		validateStruct(rv, s.name, &errs)
+	}
+	if len(errs) > 0 {
+		return nil, nil, &ValidationError{Errors: errs}
+	}
+
+	if s.validate != nil {
+		if err := s.validate(&v); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", s.name, err)
+		}
+	}
+	return &v, secretPaths, nil
+}
+
+func (s *Section[T]) load() any {
+	return s.value.Load()
+}
+
+func (s *Section[T]) store(v any) {
+	p, _ := v.(*T)
+	s.value.Store(p)
+}
+
+func (s *Section[T]) diff(oldVal, newVal any) []Change {
+	var changes []Change
+	o, _ := oldVal.(*T)
+	n, _ := newVal.(*T)
+	if o == nil {
+		o = &s.defaults
+	}
+	if n == nil {
+		n = &s.defaults
+	}
+	diffValue(reflect.ValueOf(o).Elem(), reflect.ValueOf(n).Elem(), s.name, false, &changes)
+	return changes
+}
+
+// 深拷贝 src 到 dst, 未导出字段浅拷贝
+func cloneValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				cloneValue(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			break
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			elem := reflect.New(src.Type().Elem()).Elem()
+			cloneValue(elem, iter.Value())
+			m.SetMapIndex(iter.Key(), elem)
+		}
+		dst.Set(m)
+	case reflect.Slice:
+		if src.IsNil() {
+			break
+		}
+		sl := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			cloneValue(sl.Index(i), src.Index(i))
+		}
+		dst.Set(sl)
+	case reflect.Pointer:
+		if src.IsNil() {
+			break
+		}
+		p := reflect.New(src.Type().Elem())
+		cloneValue(p.Elem(), src.Elem())
+		dst.Set(p)
+	default:
+		dst.Set(src)
+	}
+}
+
+// 按顶级键拆分主配置 JSON
+func splitSections(body []byte) (map[string]json.RawMessage, error) {
+	var raws map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		return nil, err
+	}
+	return raws, nil
+}
+
+// 已解码待生效的配置节
+type sectionValues struct {
+	body   []byte
+	values map[string]any
+
+	// 由密钥引用解析得到值的配置项路径
+	secretPaths []string
+}
+
+// 解码所有已注册的配置节, 任一配置节失败时返回聚合错误, 不更新任何配置节
+func decodeSections(body []byte) (*sectionValues, error) {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	sv := &sectionValues{body: body}
+	if len(sections) == 0 {
+		return sv, nil
+	}
+
+	raws, err := splitSections(body)
+	if err != nil {
+		return nil, err
+	}
+	sv.values = make(map[string]any, len(sections))
+	var errs []error
+	for _, s := range sections {
+		v, paths, err := s.decode(raws[s.sectionName()])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sv.values[s.sectionName()] = v
+		sv.secretPaths = append(sv.secretPaths, paths...)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return sv, nil
+}
+
+// 保存配置节新值, 返回配置节变化
+func storeSections(sv *sectionValues) []Change {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	lastConfigBody.Store(&sv.body)
+	var changes []Change
+	for _, s := range sections {
+		v, ok := sv.values[s.sectionName()]
+		if !ok {
+			continue
+		}
+		changes = append(changes, s.diff(s.load(), v)...)
+		s.store(v)
+	}
+	return changes
+}
+
+// 当前所有配置节的值
+func loadSections() map[string]any {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	values := make(map[string]any, len(sections))
+	for _, s := range sections {
+		values[s.sectionName()] = s.load()
+	}
+	return values
+}
+
+// 恢复配置节值, 返回配置节变化
+func restoreSections(values map[string]any, body *[]byte) []Change {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	lastConfigBody.Store(body)
+	var changes []Change
+	for _, s := range sections {
+		v := values[s.sectionName()]
+		changes = append(changes, s.diff(s.load(), v)...)
+		s.store(v)
+	}
+	return changes
+}
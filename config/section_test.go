@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+)
+
+type testSectionConf struct {
+	Workers int            `json:"workers" validate:"min=1"`
+	API     string         `json:"api" validate:"url"`
+	Tags    []string       `json:"tags"`
+	Limits  map[string]int `json:"limits"`
+}
+
+func TestRegisterSection(t *testing.T) {
+	oldSections, oldBody := sections, lastConfigBody.Load()
+	defer func() {
+		sections = oldSections
+		lastConfigBody.Store(oldBody)
+	}()
+	sections = nil
+	lastConfigBody.Store(nil)
+
+	defaults := testSectionConf{Workers: 4, API: "http://a.test", Limits: map[string]int{"conn": 1}}
+	biz := RegisterSection("test_biz", defaults, func(c *testSectionConf) error {
+		if c.Workers > 100 {
+			return errors.New("too many workers")
+		}
+		return nil
+	})
+	assert.Equal(t, "test_biz", biz.Name())
+	assert.Equal(t, 4, biz.Get().Workers)
+
+	assert.Panics(t, "duplicate", func() { RegisterSection("test_biz", 1, nil) })
+	assert.Panics(t, "conflict", func() { RegisterSection("web_conf", 1, nil) })
+
+	// 未出现的字段保持默认值, 环境变量覆盖生效
+	t.Setenv("FF_TEST_BIZ__LIMITS__QPS", "10")
+	sv, err := decodeSections([]byte(`{"log_conf":{},"test_biz":{"workers":8,"tags":["a"]}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, biz.Get().Workers)
+	assert.Equal(t, []string{"test_biz.workers", "test_biz.tags", "test_biz.limits"}, changePaths(storeSections(sv)))
+	assert.Equal(t, 8, biz.Get().Workers)
+	assert.Equal(t, "http://a.test", biz.Get().API)
+	assert.Equal(t, map[string]int{"conn": 1, "qps": 10}, biz.Get().Limits)
+	assert.Equal(t, map[string]int{"conn": 1}, defaults.Limits)
+
+	// 标签校验, 校验函数和环境变量错误均拒绝加载, 当前值不变
+	_, err = decodeSections([]byte(`{"test_biz":{"workers":0,"api":"x"}}`))
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, []string{"test_biz.workers", "test_biz.api"}, ve.Paths())
+
+	_, err = decodeSections([]byte(`{"test_biz":{"workers":101}}`))
+	assert.Contains(t, "too many workers", err.Error())
+
+	t.Setenv("FF_TEST_BIZ__WORKERS", "abc")
+	_, err = decodeSections([]byte(`{"test_biz":{}}`))
+	assert.Contains(t, "env FF_TEST_BIZ__WORKERS", err.Error())
+	assert.Equal(t, 8, biz.Get().Workers)
+
+	// 配置加载后注册, 立即解码
+	late := RegisterSection("test_late", map[string]int{"a": 1}, nil)
+	assert.Equal(t, map[string]int{"a": 1}, *late.Get())
+	assert.Panics(t, "invalid", func() { RegisterSection("test_biz2", testSectionConf{}, nil) })
+}
+
+func changePaths(changes []Change) []string {
+	d := &ConfigDiff{Changes: changes}
+	return d.Paths()
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"maps"
+	"net"
+	"os"
+	"slices"
+)
+
+// Snapshot 配置运行状态快照, 用于热加载失败时原子回滚
+// 包含主配置, IP 黑白名单, 参与合并的主配置文件, env 文件管理的环境变量及相关全局变量
+type Snapshot struct {
+	conf      *MainConf
+	whitelist map[*net.IPNet]int64
+	blacklist map[*net.IPNet]int64
+	alarmOn   bool
+
+	// env 文件管理的环境变量值
+	env           map[string]string
+	envFileKeys   map[string]struct{}
+	extraEnvFiles []string
+	configFiles   []string
+	layerFiles    []string
+	envOverrides  map[string]string
+	secretPaths   []string
+
+	// 应用自定义配置节
+	sections   map[string]any
+	configBody *[]byte
+
+	baseSecretEnvName   string
+	baseSecretSalt      string
+	baseSecretValue     string
+	baseSecretSecondary string
+	webTokenSalt        string
+	whitelistConfigFile string
+	blacklistConfigFile string
+	nodeInfoFile        string
+}
+
+// TakeSnapshot 保存当前配置运行状态, 在 LoadConfig 前调用
+func TakeSnapshot() *Snapshot {
+	s := &Snapshot{
+		conf:                mainConf.Load(),
+		whitelist:           Whitelist,
+		blacklist:           Blacklist,
+		alarmOn:             AlarmOn.Load(),
+		env:                 make(map[string]string, len(envFileKeys)),
+		envFileKeys:         maps.Clone(envFileKeys),
+		extraEnvFiles:       slices.Clone(extraEnvFiles),
+		configFiles:         slices.Clone(configFiles),
+		layerFiles:          slices.Clone(configLayerFiles),
+		envOverrides:        envOverrides,
+		secretPaths:         secretRefPaths,
+		sections:            loadSections(),
+		configBody:          lastConfigBody.Load(),
+		baseSecretEnvName:   BaseSecretEnvName,
+		baseSecretSalt:      BaseSecretSalt,
+		baseSecretValue:     BaseSecretValue,
+		baseSecretSecondary: BaseSecretSecondaryValue,
+		webTokenSalt:        WebTokenSalt,
+		whitelistConfigFile: WhitelistConfigFile,
+		blacklistConfigFile: BlacklistConfigFile,
+		nodeInfoFile:        NodeInfoFile,
+	}
+	for k := range envFileKeys {
+		s.env[k] = os.Getenv(k)
+	}
+	return s
+}
+
+// Config 快照中的主配置
+func (s *Snapshot) Config() *MainConf {
+	return s.conf
+}
+
+// Restore 恢复到快照时的配置运行状态
+// 恢复后 LastDiff() 为当前配置到快照配置的差异, 供重新执行 Runtime() 的 Pipeline 判断
+func (s *Snapshot) Restore() {
+	s.restoreState()
+	if s.conf != nil {
+		diff := DiffConfig(mainConf.Load(), s.conf)
+		diff.Changes = append(diff.Changes, restoreSections(s.sections, s.configBody)...)
+		redactSecretChanges(diff.Changes, slices.Concat(secretRefPaths, s.secretPaths))
+		secretRefPaths = s.secretPaths
+		lastDiff.Store(diff)
+		mainConf.Store(s.conf)
+	}
+}
+
+// 恢复环境变量和全局变量, 主配置和配置节不变
+func (s *Snapshot) restoreState() {
+	// 本次加载新增的 env 文件变量置空, 快照时存在的变量恢复原值
+	for k := range envFileKeys {
+		if _, ok := s.env[k]; !ok {
+			_ = os.Setenv(k, "")
+		}
+	}
+	for k, v := range s.env {
+		_ = os.Setenv(k, v)
+	}
+	envFileKeys = s.envFileKeys
+	extraEnvFiles = s.extraEnvFiles
+	configFiles = s.configFiles
+	configLayerFiles = s.layerFiles
+	envOverrides = s.envOverrides
+
+	Whitelist = s.whitelist
+	Blacklist = s.blacklist
+	AlarmOn.Store(s.alarmOn)
+	BaseSecretEnvName = s.baseSecretEnvName
+	BaseSecretSalt = s.baseSecretSalt
+	BaseSecretValue = s.baseSecretValue
+	BaseSecretSecondaryValue = s.baseSecretSecondary
+	WebTokenSalt = s.webTokenSalt
+	WhitelistConfigFile = s.whitelistConfigFile
+	BlacklistConfigFile = s.blacklistConfigFile
+	NodeInfoFile = s.nodeInfoFile
+}
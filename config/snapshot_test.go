@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	oldConf, oldEnvKeys := mainConf.Load(), envFileKeys
+	defer func() {
+		mainConf.Store(oldConf)
+		envFileKeys = oldEnvKeys
+	}()
+
+	t.Setenv("TEST_SNAPSHOT_KEEP", "v1")
+	t.Setenv("TEST_SNAPSHOT_NEW", "")
+	prev := &MainConf{LogConf: LogConf{Level: 1}}
+	mainConf.Store(prev)
+	envFileKeys = map[string]struct{}{"TEST_SNAPSHOT_KEEP": {}}
+	snapshot := TakeSnapshot()
+	assert.Equal(t, prev, snapshot.Config())
+
+	// 模拟热加载: 新配置, env 文件变量修改和新增
+	mainConf.Store(&MainConf{LogConf: LogConf{Level: 3}})
+	envFileKeys = map[string]struct{}{"TEST_SNAPSHOT_KEEP": {}, "TEST_SNAPSHOT_NEW": {}}
+	_ = os.Setenv("TEST_SNAPSHOT_KEEP", "v2")
+	_ = os.Setenv("TEST_SNAPSHOT_NEW", "new")
+
+	snapshot.Restore()
+	assert.Equal(t, prev, Config())
+	assert.Equal(t, "v1", os.Getenv("TEST_SNAPSHOT_KEEP"))
+	assert.Equal(t, "", os.Getenv("TEST_SNAPSHOT_NEW"))
+	assert.Equal(t, 1, len(envFileKeys))
+	assert.Equal(t, []string{"log_conf.level"}, LastDiff().Paths())
+}
+
+func TestLoadConfigFailureRestoresState(t *testing.T) {
+	oldBody, oldEnvKeys, oldEnvFiles := AppConfigBody, envFileKeys, extraEnvFiles
+	defer func() {
+		AppConfigBody, envFileKeys, extraEnvFiles = oldBody, oldEnvKeys, oldEnvFiles
+	}()
+
+	envFile := filepath.Join(t.TempDir(), "fail.env")
+	assert.Nil(t, os.WriteFile(envFile, []byte("TEST_LOAD_FAIL=new\n"), 0o644))
+	t.Setenv("TEST_LOAD_FAIL", "old")
+	envFileKeys = map[string]struct{}{"TEST_LOAD_FAIL": {}}
+	secret := BaseSecretValue
+
+	// env 文件已加载, 校验失败
+	AppConfigBody = []byte(`{"sys_conf":{"env_files":["` + envFile + `"]},"log_conf":{"level":99}}`)
+	assert.NotNil(t, LoadConfig())
+	assert.Equal(t, "old", os.Getenv("TEST_LOAD_FAIL"))
+	assert.Equal(t, 1, len(envFileKeys))
+	assert.Equal(t, secret, BaseSecretValue)
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"runtime"
+	"strings"
+)
+
+// 程序升级方式, 对应 SYSConf.UpdateStrategy
+const (
+	// UpdateStrategyDeb apt 安装指定版本的 deb 包 (默认)
+	UpdateStrategyDeb = "deb"
+	// UpdateStrategyBinary 下载指定版本的二进制文件, 校验 SHA-256 和签名后替换当前程序
+	UpdateStrategyBinary = "binary"
+)
+
+// BinaryUpdateURL 二进制文件下载地址, 替换模板变量: {version} {name} {os} {arch}
+// 如: https://dl.example.com/{name}/{version}/{name}-{os}-{arch}
+func BinaryUpdateURL(tpl, ver string) string {
+	return strings.NewReplacer(
+		"{version}", ver,
+		"{name}", BinName,
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	).Replace(tpl)
+}
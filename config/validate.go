@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateTagName 配置项约束声明的结构体标签名
+//
+// 支持的约束 (英文逗号分隔, 字符串类约束对空值不生效, 空值由 parse* 填充默认值):
+//
+//	min=N     数值下限
+//	max=N     数值上限
+//	duration  time.ParseDuration 可解析的时间间隔
+//	url       http/https 地址
+//	urls      英文逗号分隔的多个 http/https 地址
+//	addr      host:port 监听地址
+//	addrs     英文逗号分隔的多个 host:port 监听地址
+//	cidrs     字符串列表, 每项为 IP 或 CIDR
+//	oneof=A B 空格分隔的可选值之一
+const ValidateTagName = "validate"
+
+// FieldError 单个配置项校验错误
+type FieldError struct {
+	// Path 配置项 JSON 路径, 如: log_conf.level, web_conf.groups.api.server_addr
+	Path   string
+	Value  any
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s (got %v)", e.Path, e.Reason, e.Value)
+}
+
+// ValidationError 聚合的配置校验错误, 列出所有不合法的配置项
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid config: ")
+	for i, fe := range e.Errors {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+// Paths 不合法配置项的 JSON 路径列表
+func (e *ValidationError) Paths() []string {
+	paths := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		paths[i] = fe.Path
+	}
+	return paths
+}
+
+// Validate 按 validate 标签声明的约束校验配置, 返回聚合的 *ValidationError, 全部合法时返回 nil
+// 应在 JSON 解析后, 默认值修正 (parse*) 前调用, 使错误配置被拒绝而不是被静默修正
+func Validate(cfg *MainConf) error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []*FieldError
+	validateStruct(reflect.ValueOf(cfg).Elem(), "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *[]*FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := jsonFieldName(sf)
+		if name == "" {
+			continue
+		}
+		path := joinPath(prefix, name)
+		fv := v.Field(i)
+
+		if rules := sf.Tag.Get(ValidateTagName); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				if reason := checkRule(rule, fv); reason != "" {
+					*errs = append(*errs, &FieldError{Path: path, Value: fv.Interface(), Reason: reason})
+				}
+			}
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			validateStruct(fv, path, errs)
+		case reflect.Slice:
+			if fv.Type().Elem().Kind() != reflect.Struct {
+				continue
+			}
+			for i := 0; i < fv.Len(); i++ {
+				validateStruct(fv.Index(i), joinPath(path, strconv.Itoa(i)), errs)
+			}
+		case reflect.Map:
+			if fv.Type().Key().Kind() != reflect.String || fv.Type().Elem().Kind() != reflect.Struct {
+				continue
+			}
+			// 按键排序, 保证错误列表稳定
+			keys := fv.MapKeys()
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, k := range keys {
+				validateStruct(fv.MapIndex(k), joinPath(path, k.String()), errs)
+			}
+		default:
+		}
+	}
+}
+
+//nolint:cyclop
+func checkRule(rule string, v reflect.Value) string {
+	name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+	switch name {
+	case "min", "max":
+		if v.Kind() == reflect.Pointer {
+			// 未设置时不校验
+			if v.IsNil() {
+				return ""
+			}
+			return checkRule(rule, v.Elem())
+		}
+		if v.Kind() == reflect.Map {
+			// 逐个校验键值
+			keys := v.MapKeys()
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, k := range keys {
+				if reason := checkRule(rule, v.MapIndex(k)); reason != "" {
+					return k.String() + ": " + reason
+				}
+			}
+			return ""
+		}
+		limit, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return "invalid rule: " + rule
+		}
+		var n int64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = int64(v.Uint())
+		default:
+			return "invalid rule: " + rule
+		}
+		if name == "min" && n < limit {
+			return "must be >= " + arg
+		}
+		if name == "max" && n > limit {
+			return "must be <= " + arg
+		}
+	case "duration":
+		if s := v.String(); s != "" {
+			if _, err := time.ParseDuration(s); err != nil {
+				return "invalid duration"
+			}
+		}
+	case "url":
+		if s := strings.TrimSpace(v.String()); s != "" && !isHTTPURL(s) {
+			return "invalid http(s) url"
+		}
+	case "urls":
+		for _, s := range splitList(v.String()) {
+			if !isHTTPURL(s) {
+				return "invalid http(s) url: " + s
+			}
+		}
+	case "addr":
+		if s := strings.TrimSpace(v.String()); s != "" && !isListenAddr(s) {
+			return "invalid listen address"
+		}
+	case "addrs":
+		for _, s := range splitList(v.String()) {
+			if !isListenAddr(s) {
+				return "invalid listen address: " + s
+			}
+		}
+	case "cidrs":
+		if v.Kind() != reflect.Slice {
+			return "invalid rule: " + rule
+		}
+		for i := 0; i < v.Len(); i++ {
+			s := strings.TrimSpace(v.Index(i).String())
+			if net.ParseIP(s) != nil {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(s); err != nil {
+				return "invalid IP or CIDR: " + s
+			}
+		}
+	case "oneof":
+		if s := v.String(); s != "" && !slices.Contains(strings.Fields(arg), s) {
+			return "must be one of: " + arg
+		}
+	default:
+		return "unknown rule: " + rule
+	}
+	return ""
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isListenAddr(s string) bool {
+	_, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 0 && n <= 65535
+}
+
+// 英文逗号分隔的列表, 忽略空项
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// 结构体字段的 JSON 名称, 忽略 json:"-" 和无 json 标签的运行期派生字段
+func jsonFieldName(sf reflect.StructField) string {
+	if !sf.IsExported() {
+		return ""
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "" || tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/json"
+)
+
+// TestValidate 验证错误配置按 JSON 路径逐项报告, 且空值不触发字符串类约束 (由 parse* 填充默认值).
+func TestValidate(t *testing.T) {
+	assert.Nil(t, Validate(new(MainConf)))
+	assert.Nil(t, Validate(nil))
+
+	body := []byte(`{
+  "sys_conf": {"watcher_interval": "2x", "req_timeout": "3s", "canary_deployment": 101},
+  "log_conf": {"level": 8, "modules": {"a": 0, "b": -2}, "post_alarm_api": "ftp://alarm", "max_backups": -1,
+    "outputs": [{"type": "stdout"}, {"type": "kafka", "level": 9}]},
+  "main_conf": {"api": "http://conf/api?token=", "interval": -30},
+  "node_conf": {"ip_api": "https://ip.a,ip.b"},
+  "web_conf": {
+    "server_addr": ":80,  ,:8080",
+    "server_https_addr": "443",
+    "trusted_proxies": ["10.0.0.0/8", "::1", "10.1"],
+    "body_limit": -2,
+    "groups": {
+      "b": {"server_addr": ":70000"},
+      "a": {"pprof_addr": "127.0.0.1:6060", "sign_ttl": -1}
+    }
+  }
+}`)
+	cfg := new(MainConf)
+	assert.Nil(t, json.Unmarshal(body, cfg))
+
+	err := Validate(cfg)
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, []string{
+		"sys_conf.watcher_interval",
+		"sys_conf.canary_deployment",
+		"main_conf.interval",
+		"log_conf.level",
+		"log_conf.modules",
+		"log_conf.max_backups",
+		"log_conf.post_alarm_api",
+		"log_conf.outputs.1.type",
+		"log_conf.outputs.1.level",
+		"node_conf.ip_api",
+		"web_conf.server_https_addr",
+		"web_conf.groups.a.sign_ttl",
+		"web_conf.groups.b.server_addr",
+		"web_conf.trusted_proxies",
+		"web_conf.body_limit",
+	}, ve.Paths())
+	assert.Contains(t, "log_conf.level: must be <= 7 (got 8)", err.Error())
+	assert.Contains(t, "log_conf.modules: b: must be >= -1", err.Error())
+	assert.Contains(t, "node_conf.ip_api: invalid http(s) url: ip.b", err.Error())
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fufuok/pkg/json"
+)
+
+const (
+	VerifyEd25519    = "ed25519"
+	VerifyHMACSHA256 = "hmac-sha256"
+
+	DefaultVerifyHeader       = "X-Signature"
+	DefaultVerifyPayloadField = "data"
+)
+
+// ErrDataSourceVerify 远端配置内容校验失败 (签名或格式), 本地文件未更新
+var ErrDataSourceVerify = errors.New("data source verification failed")
+
+var (
+	verifyFailuresMu sync.Mutex
+	verifyFailures   = make(map[string]*VerifyFailure)
+)
+
+// VerifyConf 远端配置内容校验
+//
+// 签名: 对配置内容 (信封模式时为 payload_field 字段值) 计算 Ed25519 签名或 HMAC-SHA256, base64 或 hex 编码,
+// 放在响应头 header 中, 或 JSON 信封的 field 字段中, 如: {"signature": "...", "data": "..."}
+type VerifyConf struct {
+	// Method 签名算法: ed25519, hmac-sha256, 留空不校验签名
+	Method string `json:"method" validate:"oneof=ed25519 hmac-sha256"`
+	// PublicKey Ed25519 公钥, base64 或 hex 编码
+	PublicKey string `json:"public_key"`
+	// KeyName HMAC 密钥环境变量名, 值使用基础密钥加密或为密钥引用
+	KeyName string `json:"key_name"`
+	// Header 签名响应头, 默认: X-Signature
+	Header string `json:"header"`
+	// Field 信封签名字段名, 设置时响应内容为 JSON 信封
+	Field string `json:"field"`
+	// PayloadField 信封配置内容字段名, 默认: data, 值为字符串时取字符串内容, 否则取原始 JSON
+	PayloadField string `json:"payload_field"`
+	// Format 内容格式校验: json, lines (文本行), ip (IP/CIDR 名单, 同白名单文件格式)
+	Format string `json:"format" validate:"oneof=json lines ip"`
+	// Key HMAC 密钥, 由 KeyName 解密得到
+	Key string `json:"-" secret:"true"`
+}
+
+// VerifyFailure 远端配置校验失败统计
+type VerifyFailure struct {
+	Count     uint64
+	LastError string
+	LastTime  time.Time
+}
+
+// VerifyDataSource 校验远端配置内容签名, 返回待写入的配置内容 (信封模式时为 payload 字段值)
+// header 为响应头, 无响应头的数据源 (如 Redis) 传 nil, 仅支持信封模式
+// 校验失败时返回 ErrDataSourceVerify 包装的错误, 并计入 VerifyFailures()
+func VerifyDataSource(cfg FilesConf, body []byte, header http.Header) ([]byte, error) {
+	vc := cfg.Verify
+	if vc.Method == "" {
+		return body, nil
+	}
+
+	var sig string
+	payload := body
+	if vc.Field != "" {
+		var env map[string]json.RawMessage
+		if err := json.Unmarshal(body, &env); err != nil {
+			return nil, verifyFailed(cfg, fmt.Errorf("invalid signed envelope: %w", err))
+		}
+		if err := json.Unmarshal(env[vc.Field], &sig); err != nil {
+			return nil, verifyFailed(cfg, fmt.Errorf("invalid signature field: %s", vc.Field))
+		}
+		raw, ok := env[defaultString(vc.PayloadField, DefaultVerifyPayloadField)]
+		if !ok {
+			return nil, verifyFailed(cfg, errors.New("signed payload not found"))
+		}
+		payload = raw
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			payload = []byte(s)
+		}
+	} else if header != nil {
+		sig = header.Get(defaultString(vc.Header, DefaultVerifyHeader))
+	}
+	if err := verifyPayload(cfg, payload, sig); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// 校验配置内容签名, 失败时计入统计
+func verifyPayload(cfg FilesConf, payload []byte, sig string) error {
+	if sig == "" {
+		return verifyFailed(cfg, errors.New("signature not found"))
+	}
+	if err := verifySignature(cfg.Verify, payload, sig); err != nil {
+		return verifyFailed(cfg, err)
+	}
+	return nil
+}
+
+// CheckDataSourceFormat 校验远端配置内容格式
+func CheckDataSourceFormat(format string, body []byte) error {
+	switch format {
+	case "":
+	case "json":
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return fmt.Errorf("invalid json content: %w", err)
+		}
+	case "lines", "ip":
+		lines := strings.Split(string(bytes.TrimSpace(body)), "\n")
+		for i, line := range lines {
+			if strings.ContainsFunc(line, func(r rune) bool { return r < ' ' && r != '\t' && r != '\r' }) {
+				return fmt.Errorf("invalid content at line %d", i+1)
+			}
+		}
+		if format == "ip" {
+			if _, err := getIPNetList(lines); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown verify format: %s", format)
+	}
+	return nil
+}
+
+// VerifyFailures 各远端配置文件 (路径) 内容校验失败统计
+func VerifyFailures() map[string]VerifyFailure {
+	verifyFailuresMu.Lock()
+	defer verifyFailuresMu.Unlock()
+	res := make(map[string]VerifyFailure, len(verifyFailures))
+	for k, v := range verifyFailures {
+		res[k] = *v
+	}
+	return res
+}
+
+func verifyFailed(cfg FilesConf, err error) error {
+	verifyFailuresMu.Lock()
+	f, ok := verifyFailures[cfg.Path]
+	if !ok {
+		f = new(VerifyFailure)
+		verifyFailures[cfg.Path] = f
+	}
+	f.Count++
+	f.LastError = err.Error()
+	f.LastTime = time.Now()
+	verifyFailuresMu.Unlock()
+	return fmt.Errorf("%w: %s: %w", ErrDataSourceVerify, cfg.Path, err)
+}
+
+func verifySignature(vc VerifyConf, payload []byte, signature string) error {
+	sig, err := decodeKey(signature)
+	if err != nil {
+		return errors.New("invalid signature encoding")
+	}
+	switch vc.Method {
+	case VerifyEd25519:
+		return verifyEd25519(vc.PublicKey, payload, sig)
+	case VerifyHMACSHA256:
+		if vc.Key == "" {
+			return errors.New("hmac key cannot be empty")
+		}
+		h := hmac.New(sha256.New, []byte(vc.Key))
+		h.Write(payload)
+		if !hmac.Equal(h.Sum(nil), sig) {
+			return errors.New("hmac signature mismatch")
+		}
+	default:
+		return fmt.Errorf("unknown verify method: %s", vc.Method)
+	}
+	return nil
+}
+
+// CheckEd25519Signature 使用 Ed25519 公钥校验签名, 公钥和签名均为 base64 或 hex 编码
+func CheckEd25519Signature(publicKey string, message []byte, signature string) error {
+	sig, err := decodeKey(signature)
+	if err != nil {
+		return errors.New("invalid signature encoding")
+	}
+	return verifyEd25519(publicKey, message, sig)
+}
+
+func verifyEd25519(publicKey string, message, sig []byte) error {
+	pub, err := decodeKey(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid ed25519 public key")
+	}
+	if !ed25519.Verify(pub, message, sig) {
+		return errors.New("ed25519 signature mismatch")
+	}
+	return nil
+}
+
+// 解析配置内容校验参数: HMAC 密钥, Ed25519 公钥
+func parseVerifyConfig(vc *VerifyConf, secret string) error {
+	switch vc.Method {
+	case VerifyEd25519:
+		if pub, err := decodeKey(vc.PublicKey); err != nil || len(pub) != ed25519.PublicKeySize {
+			return errors.New("verify.public_key: invalid ed25519 public key")
+		}
+	case VerifyHMACSHA256:
+		key, err := GetenvSecret(vc.KeyName, secret)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			return fmt.Errorf("verify.key_name: %s cannot be empty", vc.KeyName)
+		}
+		vc.Key = key
+	}
+	return nil
+}
+
+// hex 或 base64 (标准/URL, 可无填充) 解码
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("invalid key encoding")
+}
+
+func defaultString(s, defaultValue string) string {
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestVerifyDataSource(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	payload := []byte(`{"name":"ff"}`)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+
+	cfg := FilesConf{Path: "ed25519.json", Verify: VerifyConf{
+		Method:    VerifyEd25519,
+		PublicKey: hex.EncodeToString(pub),
+	}}
+	body, err := VerifyDataSource(cfg, payload, http.Header{DefaultVerifyHeader: {sig}})
+	assert.Nil(t, err)
+	assert.Equal(t, payload, body)
+
+	_, err = VerifyDataSource(cfg, []byte(`{"name":"xx"}`), http.Header{DefaultVerifyHeader: {sig}})
+	assert.True(t, errors.Is(err, ErrDataSourceVerify))
+	_, err = VerifyDataSource(cfg, payload, nil)
+	assert.True(t, errors.Is(err, ErrDataSourceVerify))
+	assert.Equal(t, uint64(2), VerifyFailures()["ed25519.json"].Count)
+
+	// HMAC 信封
+	mac := hmac.New(sha256.New, []byte("key~~1"))
+	mac.Write([]byte("1.1.1.1\n2.2.2.0/24"))
+	env := `{"signature":"` + hex.EncodeToString(mac.Sum(nil)) + `","data":"1.1.1.1\n2.2.2.0/24"}`
+	cfg = FilesConf{Path: "hmac.txt", Verify: VerifyConf{Method: VerifyHMACSHA256, Key: "key~~1", Field: "signature"}}
+	body, err = VerifyDataSource(cfg, []byte(env), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.1.1.1\n2.2.2.0/24", string(body))
+
+	cfg.Verify.Key = "key~~2"
+	_, err = VerifyDataSource(cfg, []byte(env), nil)
+	assert.True(t, errors.Is(err, ErrDataSourceVerify))
+}
+
+func TestCheckDataSourceFormat(t *testing.T) {
+	assert.Nil(t, CheckDataSourceFormat("", []byte("\x00")))
+	assert.Nil(t, CheckDataSourceFormat("json", []byte(`{"a":1}`)))
+	assert.NotNil(t, CheckDataSourceFormat("json", []byte(`{"a":`)))
+	assert.Nil(t, CheckDataSourceFormat("lines", []byte("a\nb\r\n")))
+	assert.NotNil(t, CheckDataSourceFormat("lines", []byte("a\x00b")))
+	assert.Nil(t, CheckDataSourceFormat("ip", []byte("# 注释\n1.1.1.1\n10.0.0.0/8,100,内网\n::1\n")))
+	assert.NotNil(t, CheckDataSourceFormat("ip", []byte("1.1.1.1\n<html>")))
+	assert.NotNil(t, CheckDataSourceFormat("xml", nil))
+}
+
+func TestGetHTTPSourceVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DefaultVerifyHeader, "bad")
+		_, _ = w.Write([]byte("1.1.1.1\n"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "whitelist.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("2.2.2.2\n"), 0o600))
+	cfg := FilesConf{Path: path, API: srv.URL, Verify: VerifyConf{Method: VerifyHMACSHA256, Key: "key"}}
+	err := GetHTTPSource(DataSourceArgs{Conf: cfg})
+	assert.True(t, errors.Is(err, ErrDataSourceVerify))
+
+	// 校验失败时不覆盖本地文件
+	b, _ := os.ReadFile(path)
+	assert.Equal(t, "2.2.2.2\n", string(b))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/fufuok/cache/xsync"
 	"github.com/fufuok/cron"
+	"github.com/rs/zerolog"
 
 	"github.com/fufuok/pkg/common"
 	"github.com/fufuok/pkg/config"
@@ -20,6 +21,9 @@ var (
 	skipIfStillRunning atomic.Bool
 )
 
+// LogModuleName 定时任务日志的模块名, 可通过 log_conf.modules 单独设置日志级别
+const LogModuleName = "crontab"
+
 type M struct{}
 
 // Start 程序启动时初始化
@@ -36,10 +40,15 @@ func (m *M) Runtime() error {
 // Stop 程序退出时运行
 func (m *M) Stop() error {
 	crontab.Stop()
-	logger.Warn().Msg("Crontab exited")
+	logModule().Warn().Msg("Crontab exited")
 	return nil
 }
 
+// 定时任务模块日志
+func logModule() *zerolog.Logger {
+	return logger.Module(LogModuleName)
+}
+
 // SetSkipIfStillRunning 全局设置任务是否单例执行
 func SetSkipIfStillRunning(v bool) {
 	skipIfStillRunning.Store(v)
@@ -61,5 +70,5 @@ func initMain() {
 	}
 	crontab = cron.New(opts...)
 	crontab.Start()
-	logger.Info().Msg("crontab started")
+	logModule().Info().Msg("crontab started")
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,7 +16,6 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/fufuok/pkg/common"
-	"github.com/fufuok/pkg/logger"
 	"github.com/fufuok/pkg/logger/alarm"
 )
 
@@ -37,6 +38,12 @@ type Job struct {
 	// 附加的任务字段
 	fields map[string]any
 
+	// 恢复暂停的任务时使用
+	parent context.Context
+	runner Runner
+	once   bool
+	opts   []cron.EntryOption
+
 	id     cron.EntryID
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -47,6 +54,9 @@ type Job struct {
 	// 任务是否已被执行过
 	executed atomic.Bool
 
+	// 任务是否被暂停 (保留在任务列表中, 可恢复)
+	paused atomic.Bool
+
 	// 单例执行锁
 	runningMu sync.Mutex
 }
@@ -69,15 +79,29 @@ func (j *Job) Prev() time.Time {
 	return crontab.Entry(j.id).Prev
 }
 
+func (j *Job) Spec() string {
+	return j.spec
+}
+
 func (j *Job) IsRunning() bool {
 	return j.running.Load()
 }
 
+func (j *Job) IsPaused() bool {
+	return j.paused.Load()
+}
+
 func (j *Job) Stop() {
+	if j.paused.CompareAndSwap(true, false) {
+		// 暂停时已移出调度器
+		logModule().Warn().Str("job", j.name).Str("cron", j.spec).Msg("Paused job stopped")
+		jobs.Delete(j.name)
+		return
+	}
 	if !j.runningToStop() {
 		return
 	}
-	logger.Warn().Str("job", j.name).Str("cron", j.spec).Time("prev", j.Prev()).Msg("Job stopped")
+	logModule().Warn().Str("job", j.name).Str("cron", j.spec).Time("prev", j.Prev()).Msg("Job stopped")
 	jobs.Delete(j.name)
 	crontab.Remove(j.id)
 	if j.cancel != nil {
@@ -85,14 +109,42 @@ func (j *Job) Stop() {
 	}
 }
 
+// Pause 暂停任务调度, 任务保留在任务列表中, 可通过 Resume 恢复
+// 执行中的任务会收到 ctx 取消信号
+func (j *Job) Pause() bool {
+	if !j.runningToStop() {
+		return false
+	}
+	j.paused.Store(true)
+	logModule().Warn().Str("job", j.name).Str("cron", j.spec).Time("prev", j.Prev()).Msg("Job paused")
+	crontab.Remove(j.id)
+	if j.cancel != nil {
+		j.cancel()
+	}
+	return true
+}
+
+// Resume 恢复暂停的任务
+func (j *Job) Resume() (bool, error) {
+	if !j.paused.CompareAndSwap(true, false) {
+		return false, nil
+	}
+	if _, err := j.start(j.parent, j.runner, j.once, j.opts...); err != nil {
+		j.paused.Store(true)
+		return false, err
+	}
+	return true, nil
+}
+
 // 添加或更新任务, 返回工作中的任务对象
 func (j *Job) start(ctx context.Context, r Runner, once bool, opts ...cron.EntryOption) (*Job, error) {
+	j.parent, j.runner, j.once, j.opts = ctx, r, once, opts
 	j.ctx, j.cancel = context.WithCancel(ctx)
 	cmd := func() {
 		if skipIfStillRunning.Load() {
 			// 每任务单例执行, 不允许任务重叠
 			if !j.runningMu.TryLock() {
-				logger.Warn().Str("job", j.name).Bool("real_blocked", IsRealBlocked() != nil).
+				logModule().Warn().Str("job", j.name).Bool("real_blocked", IsRealBlocked() != nil).
 					Msg("Job overlapped and were skipped")
 				return
 			}
@@ -100,13 +152,13 @@ func (j *Job) start(ctx context.Context, r Runner, once bool, opts ...cron.Entry
 		}
 
 		if once && !j.executed.CompareAndSwap(false, true) {
-			logger.Info().Str("job", j.name).Msg("once job already executed, skipping")
+			logModule().Info().Str("job", j.name).Msg("once job already executed, skipping")
 			return
 		}
 
 		start := time.Now()
 		rid := xid.NewString()
-		logger.Info().Str("job", j.name).Str("rid", rid).Msg("starting job")
+		logModule().Info().Str("job", j.name).Str("rid", rid).Msg("starting job")
 
 		err := r.Run(j.ctx)
 		if err != nil {
@@ -114,7 +166,7 @@ func (j *Job) start(ctx context.Context, r Runner, once bool, opts ...cron.Entry
 			j.addLogFields(logEvent).Msg("Job execution failed")
 		}
 
-		logger.Info().Str("job", j.name).Str("rid", rid).Dur("took", time.Since(start)).Msg("job completed")
+		logModule().Info().Str("job", j.name).Str("rid", rid).Dur("took", time.Since(start)).Msg("job completed")
 
 		if once {
 			j.Stop()
@@ -130,7 +182,7 @@ func (j *Job) start(ctx context.Context, r Runner, once bool, opts ...cron.Entry
 	j.running.Store(true)
 	jobs.Store(j.name, j)
 
-	logger.Warn().Str("job", j.name).Str("cron", j.spec).Time("next", j.Next()).Msg("Job added")
+	logModule().Warn().Str("job", j.name).Str("cron", j.spec).Time("next", j.Next()).Msg("Job added")
 	return j, nil
 }
 
@@ -176,7 +228,7 @@ func AddOnceJobWithFields(ctx context.Context, name, spec string, runner Runner,
 func addJob(ctx context.Context, name, spec string, runner Runner, once bool, fields map[string]any, opts ...cron.EntryOption) (*Job, error) {
 	if job, ok := GetJob(name); ok {
 		if job.IsRunning() && job.spec == spec {
-			logger.Info().Str(name, spec).Msg("skipping job add")
+			logModule().Info().Str(name, spec).Msg("skipping job add")
 			return job, nil
 		}
 		job.Stop()
@@ -196,7 +248,7 @@ func GetJob(name string) (*Job, bool) {
 
 // StopJob 通过名称停止任务
 func StopJob(name string) bool {
-	logger.Info().Str("job", name).Msg("stopping job")
+	logModule().Info().Str("job", name).Msg("stopping job")
 	if j, ok := GetJob(name); ok {
 		j.Stop()
 		return true
@@ -204,6 +256,35 @@ func StopJob(name string) bool {
 	return false
 }
 
+// PauseJob 通过名称暂停任务
+func PauseJob(name string) bool {
+	if j, ok := GetJob(name); ok {
+		return j.Pause()
+	}
+	return false
+}
+
+// ResumeJob 通过名称恢复暂停的任务
+func ResumeJob(name string) (bool, error) {
+	if j, ok := GetJob(name); ok {
+		return j.Resume()
+	}
+	return false, nil
+}
+
+// Jobs 任务列表 (含暂停的任务), 按名称排序
+func Jobs() []*Job {
+	list := make([]*Job, 0, jobs.Size())
+	jobs.Range(func(_ string, j *Job) bool {
+		list = append(list, j)
+		return true
+	})
+	slices.SortFunc(list, func(a, b *Job) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return list
+}
+
 // IsRealBlocked 场景:
 // 任务设置了立即执行, 00:59.999 刚开始执行,
 // 下次执行时间 01:00 跟着就到了, 再次启动了任务, 但没抢到锁, 忽略该次 Blocked
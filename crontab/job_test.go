@@ -255,6 +255,39 @@ func TestStopJob(t *testing.T) {
 	})
 }
 
+func TestPauseResumeJob(t *testing.T) {
+	mockRunner := &MockRunner{}
+	job, err := AddJob(context.Background(), "pause_test", "@every 1s", mockRunner)
+	assert.Nil(t, err)
+	defer job.Stop()
+
+	// 暂停后保留在任务列表中
+	assert.True(t, PauseJob("pause_test"))
+	assert.False(t, PauseJob("pause_test"))
+	got, exists := GetJob("pause_test")
+	assert.True(t, exists)
+	assert.True(t, got.IsPaused())
+	assert.False(t, got.IsRunning())
+	assert.True(t, got.Next().IsZero())
+
+	ok, err := ResumeJob("pause_test")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, job.IsRunning())
+	assert.False(t, job.IsPaused())
+	assert.False(t, job.Next().IsZero())
+
+	ok, err = ResumeJob("pause_test")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// 暂停的任务可直接停止
+	assert.True(t, job.Pause())
+	assert.True(t, StopJob("pause_test"))
+	_, exists = GetJob("pause_test")
+	assert.False(t, exists)
+}
+
 func TestJobContextCancellation(t *testing.T) {
 	t.Run("context_cancellation_stops_job", func(t *testing.T) {
 		mockRunner := &MockRunner{}
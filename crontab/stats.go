@@ -15,6 +15,7 @@ func DataStats() *jsongen.Map {
 		js := jsongen.NewMap()
 		js.PutString("prev_run", j.Prev().Format(time.RFC3339))
 		js.PutString("next_run", j.Next().Format(time.RFC3339))
+		js.PutBool("paused", j.IsPaused())
 		jss.PutMap(name, js)
 		return true
 	})
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fufuok/utils"
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/master"
+)
+
+var (
+	// DiskMinFree 日志目录 (config.LogPath) 所在磁盘的最小可用空间, 0 为不检查
+	DiskMinFree uint64 = 512 << 20
+
+	// NtpdateWait 时间同步检查等待首次同步完成的时间
+	NtpdateWait = 100 * time.Millisecond
+)
+
+// 内置检查: 配置加载, 时间同步, Redis, 日志磁盘空间, Pipeline 状态
+func init() {
+	Register("config", Liveness|Readiness, CheckConfig)
+	Register("pipelines", Readiness, CheckPipelines)
+	Register("ntpdate", Readiness, CheckNtpdate)
+	Register("redis", Readiness, CheckRedis)
+	Register("disk", Readiness, CheckDisk)
+}
+
+// CheckConfig 主配置已加载
+func CheckConfig(context.Context) error {
+	if !config.ConfigInitialized || config.Config() == nil {
+		return errors.New("config not loaded")
+	}
+	return nil
+}
+
+// CheckPipelines 所有 Pipeline 均已启动且未失败
+func CheckPipelines(context.Context) error {
+	var errs []error
+	for _, st := range master.Status() {
+		if st.State != master.PipelineRunning {
+			errs = append(errs, fmt.Errorf("%s: %s %s", st.Name, st.State, st.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckNtpdate 已配置时间同步时, 首次同步已完成
+func CheckNtpdate(context.Context) error {
+	if cfg := config.Config(); cfg == nil || cfg.SYSConf.TimeSyncType == "" {
+		return ErrSkipped
+	}
+	if !master.WaitUntilNtpdate(NtpdateWait) {
+		return errors.New("time sync not done")
+	}
+	return nil
+}
+
+// CheckRedis 已初始化 common.RedisDB 时, Redis 可连接
+func CheckRedis(ctx context.Context) error {
+	if !common.RedisDBInited.Load() {
+		return ErrSkipped
+	}
+	return common.RedisDB.Ping(ctx).Err()
+}
+
+// CheckDisk 日志目录所在磁盘可用空间不低于 DiskMinFree
+func CheckDisk(ctx context.Context) error {
+	if DiskMinFree == 0 || config.LogPath == "" {
+		return ErrSkipped
+	}
+	usage, err := disk.UsageWithContext(ctx, config.LogPath)
+	if err != nil {
+		return err
+	}
+	if usage.Free < DiskMinFree {
+		return fmt.Errorf("disk free %s < %s: %s",
+			utils.HumanIBytes(usage.Free), utils.HumanIBytes(DiskMinFree), config.LogPath)
+	}
+	return nil
+}
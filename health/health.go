@@ -0,0 +1,197 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Kind 检查类型, 可组合: Liveness | Readiness
+type Kind uint8
+
+const (
+	// Liveness 存活检查 (/healthz), 失败时通常由编排系统重启程序
+	Liveness Kind = 1 << iota
+	// Readiness 就绪检查 (/readyz), 失败时通常摘除流量
+	Readiness
+)
+
+// 检查结果状态
+const (
+	StatusOK      = "ok"
+	StatusFail    = "fail"
+	StatusSkipped = "skipped"
+)
+
+// ErrSkipped 检查项不适用 (如未启用 Redis), 不影响整体状态
+var ErrSkipped = errors.New("skipped")
+
+// CheckTimeout 单个检查的超时时间
+var CheckTimeout = 3 * time.Second
+
+// Check 健康检查函数, ctx 带有 CheckTimeout 超时
+type Check func(ctx context.Context) error
+
+// Result 单个检查结果
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+	// LastError 最近一次失败的错误和时间, 检查恢复后保留
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// Report 检查汇总, 任一检查失败时 Status 为 fail
+type Report struct {
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+	Checks []Result  `json:"checks"`
+}
+
+// Redacted 仅含整体状态的检查汇总, 用于返回给非白名单客户端
+func (r Report) Redacted() Report {
+	return Report{Status: r.Status, Time: r.Time}
+}
+
+type checker struct {
+	name  string
+	kind  Kind
+	check Check
+
+	mu            sync.Mutex
+	lastError     string
+	lastErrorTime time.Time
+}
+
+var (
+	checkersMu sync.RWMutex
+	checkers   []*checker
+)
+
+// Register 注册或替换同名检查
+func Register(name string, kind Kind, check Check) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	c := &checker{name: name, kind: kind, check: check}
+	if i := slices.IndexFunc(checkers, func(c *checker) bool { return c.name == name }); i >= 0 {
+		checkers[i] = c
+		return
+	}
+	checkers = append(checkers, c)
+}
+
+// Unregister 删除检查
+func Unregister(name string) {
+	checkersMu.Lock()
+	checkers = slices.DeleteFunc(checkers, func(c *checker) bool { return c.name == name })
+	checkersMu.Unlock()
+}
+
+// Names 已注册的检查名称
+func Names() []string {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+	names := make([]string, len(checkers))
+	for i, c := range checkers {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Live 执行所有存活检查
+func Live(ctx context.Context) Report {
+	return Run(ctx, Liveness)
+}
+
+// Ready 执行所有就绪检查
+func Ready(ctx context.Context) Report {
+	return Run(ctx, Readiness)
+}
+
+// Run 并发执行指定类型的检查
+func Run(ctx context.Context, kind Kind) Report {
+	checkersMu.RLock()
+	var cs []*checker
+	for _, c := range checkers {
+		if c.kind&kind != 0 {
+			cs = append(cs, c)
+		}
+	}
+	checkersMu.RUnlock()
+
+	report := Report{
+		Status: StatusOK,
+		Time:   time.Now(),
+		Checks: make([]Result, len(cs)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range cs {
+		wg.Go(func() {
+			report.Checks[i] = c.run(ctx)
+		})
+	}
+	wg.Wait()
+	for _, r := range report.Checks {
+		if r.Status == StatusFail {
+			report.Status = StatusFail
+			break
+		}
+	}
+	return report
+}
+
+func (c *checker) run(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := safeCheck(ctx, c.check)
+	res := Result{
+		Name:    c.name,
+		Status:  StatusOK,
+		Latency: time.Since(start).String(),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrSkipped):
+		res.Status = StatusSkipped
+	default:
+		res.Status = StatusFail
+		res.Error = err.Error()
+		c.lastError = res.Error
+		c.lastErrorTime = start
+	}
+	if c.lastError != "" {
+		t := c.lastErrorTime
+		res.LastError = c.lastError
+		res.LastErrorTime = &t
+	}
+	return res
+}
+
+// 执行检查, 超时或崩溃时返回错误
+func safeCheck(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
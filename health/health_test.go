@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestRun(t *testing.T) {
+	fail := errors.New("down")
+	var healthy bool
+	Register("test_ok", Liveness|Readiness, func(context.Context) error { return nil })
+	Register("test_skip", Readiness, func(context.Context) error { return ErrSkipped })
+	Register("test_flaky", Readiness, func(context.Context) error {
+		if healthy {
+			return nil
+		}
+		return fail
+	})
+	defer func() {
+		Unregister("test_ok")
+		Unregister("test_skip")
+		Unregister("test_flaky")
+	}()
+	assert.True(t, slices.Contains(Names(), "test_flaky"))
+
+	report := Run(context.Background(), Readiness)
+	res := map[string]Result{}
+	for _, r := range report.Checks {
+		res[r.Name] = r
+	}
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, res["test_ok"].Status)
+	assert.Equal(t, StatusSkipped, res["test_skip"].Status)
+	assert.Equal(t, "down", res["test_flaky"].Error)
+
+	// 恢复后保留最近一次错误
+	healthy = true
+	for _, r := range Run(context.Background(), Readiness).Checks {
+		if r.Name == "test_flaky" {
+			assert.Equal(t, StatusOK, r.Status)
+			assert.Equal(t, "", r.Error)
+			assert.Equal(t, "down", r.LastError)
+			assert.NotNil(t, r.LastErrorTime)
+		}
+	}
+
+	// 存活检查不包含就绪检查项
+	for _, r := range Live(context.Background()).Checks {
+		assert.NotEqual(t, "test_flaky", r.Name)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	timeout := CheckTimeout
+	CheckTimeout = 10 * time.Millisecond
+	defer func() {
+		CheckTimeout = timeout
+	}()
+	Register("test_slow", Liveness, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	Register("test_panic", Liveness, func(ctx context.Context) error {
+		panic("boom")
+	})
+	defer func() {
+		Unregister("test_slow")
+		Unregister("test_panic")
+	}()
+	for _, r := range Live(context.Background()).Checks {
+		switch r.Name {
+		case "test_slow":
+			assert.Equal(t, context.DeadlineExceeded.Error(), r.Error)
+		case "test_panic":
+			assert.Equal(t, "check panic: boom", r.Error)
+		}
+	}
+}
@@ -127,3 +127,10 @@ func Printf(format string, v ...any) {
 func Ctx(ctx context.Context) *zerolog.Logger {
 	return zerolog.Ctx(ctx)
 }
+
+// Module returns the named sub-logger with the module field added to its context.
+// Its level can be overridden by log_conf.modules or changed at runtime,
+// see common.SetLogModuleLevel and common.DebugLogFor.
+func Module(name string) *zerolog.Logger {
+	return common.LogModule(name)
+}
@@ -34,10 +34,11 @@ var (
 	verRegexpTpl = `\s%s\s+([\w.-]+)\s`
 )
 
-func installDeb(ver string) {
+// 安装指定版本的 deb 包, 返回是否安装成功
+func installDeb(ver string) bool {
 	if !debInstalling.CompareAndSwap(false, true) {
 		logger.Warn().Str("ver", ver).Msg("Deb installation skipped")
-		return
+		return false
 	}
 	defer debInstalling.Store(false)
 
@@ -67,13 +68,14 @@ func installDeb(ver string) {
 			Str("deb", deb).Strs("stdout", status.Stdout).Strs("stderr", status.Stderr).
 			Strs("cmd", installCmd).
 			Msg("Deb package installation failed")
-	} else {
-		config.DebVersion = ver
-		logger.Warn().Str("deb", deb).Float64("took_s", status.Runtime).
-			Strs("stdout", status.Stdout).Strs("stderr", status.Stderr).
-			Strs("cmd", installCmd).
-			Msg("Deb package installed")
+		return false
 	}
+	config.DebVersion = ver
+	logger.Warn().Str("deb", deb).Float64("took_s", status.Runtime).
+		Strs("stdout", status.Stdout).Strs("stderr", status.Stderr).
+		Strs("cmd", installCmd).
+		Msg("Deb package installed")
+	return true
 }
 
 // 判断是否满足安装条件
@@ -85,8 +87,11 @@ func canary(ver string, threshold uint64) bool {
 	return v < threshold
 }
 
-// 获取当前安装的包版本
+// 获取当前安装的包版本, 二进制升级方式时为当前程序版本
 func getCurrentDebVersion() string {
+	if config.Config().SYSConf.UpdateStrategy == config.UpdateStrategyBinary {
+		return BinaryVersion()
+	}
 	return DebVersion(config.DebName)
 }
 
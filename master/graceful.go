@@ -0,0 +1,153 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/logger"
+)
+
+// 新进程启动失败或未就绪, 旧进程的服务未受影响, 应继续运行
+var errGracefulNotReady = errors.New("new process is not ready")
+
+// 平滑重启: 启动新进程并传递所有监听器, 新进程就绪后停止旧进程的配置监控和远程配置任务,
+// 关闭服务并等待已有请求处理完成, 返回后旧进程即可退出.
+// 新进程不再由旧进程等待, 由进程管理器接管: systemd 下通过 NOTIFY_SOCKET 通知新的 MAINPID (需 Type=notify),
+// 内置守护进程 (-d) 无法接管新进程, 返回错误, 由守护进程重启程序.
+// 新进程启动失败或未在超时时间内就绪时返回 errGracefulNotReady, 旧进程的服务未受影响
+func gracefulRestart(stopRemote context.CancelFunc) error {
+	if Daemon && !config.Debug {
+		return errors.New("not supported under the built-in daemon")
+	}
+	timeout := config.Config().SYSConf.GracefulTimeoutDuration
+	addrs, files, err := common.ListenerFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no listeners to hand off")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		closeFiles(files)
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	cmd := exec.Command(mainFile, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	cmd.Env = append(os.Environ(),
+		config.GracefulListenFdsEnvName+"="+strings.Join(addrs, ","),
+		config.GracefulReadyFdEnvName+"="+strconv.Itoa(3+len(files)),
+	)
+	err = cmd.Start()
+	_ = w.Close()
+	closeFiles(files)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errGracefulNotReady, err)
+	}
+
+	// 等待新进程就绪, 新进程异常退出时管道关闭, 读取返回 EOF
+	_ = r.SetReadDeadline(time.Now().Add(timeout))
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("%w: %w", errGracefulNotReady, err)
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	if err := notifyMainPID(pid); err != nil {
+		logger.Warn().Err(err).Int("pid", pid).Msg("Failed to notify supervisor of new main pid")
+	}
+	logger.Warn().Int("pid", pid).Strs("listeners", addrs).Msg("New process ready, draining")
+
+	// 移交后旧进程不再响应配置变化, 避免与新进程重复执行重启, 升级等操作
+	stopRemote()
+	stopWatcher()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	if err := common.Shutdown(ctx); err != nil {
+		logger.Warn().Err(err).Dur("timeout", timeout).Msg("Graceful shutdown failed")
+	}
+	cancel()
+	stopPipelineOnce()
+	logger.Warn().Int("pid", pid).Msg("Drained, exiting")
+	return nil
+}
+
+// 通知 systemd 服务主进程变更为新进程, 非 systemd notify 服务时忽略
+func notifyMainPID(pid int) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err = conn.Write([]byte("MAINPID=" + strconv.Itoa(pid)))
+	return err
+}
+
+// 平滑重启的新进程: 等待继承的监听器均被重新使用 (Web 服务启动), 且所有 Pipeline 运行正常,
+// ReloadProbe 检查通过后通知旧进程. 未就绪时停止并退出, 旧进程读取到 EOF 后继续提供服务
+func notifyGracefulReady() {
+	if !common.GracefulInherited() {
+		return
+	}
+	timeout := config.Config().SYSConf.GracefulTimeoutDuration
+	deadline := time.Now().Add(timeout / 2)
+	for len(common.PendingListeners()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if pending := common.PendingListeners(); len(pending) > 0 {
+		logger.Warn().Strs("listeners", pending).Msg("Inherited listeners not used, closed")
+	}
+	if err := checkGracefulReady(); err != nil {
+		logger.Error().Err(err).Int("ppid", os.Getppid()).Msg("Graceful restart not ready, exiting")
+		stopPipelineOnce()
+		os.Exit(1)
+	}
+	if err := common.NotifyGracefulReady(); err != nil {
+		logger.Error().Err(err).Msg("Failed to notify graceful ready")
+		return
+	}
+	logger.Warn().Int("ppid", os.Getppid()).Msg("Graceful restart ready")
+}
+
+// 新进程就绪检查: 所有 Pipeline 均在运行中, 且 ReloadProbe 检查通过
+func checkGracefulReady() error {
+	for _, st := range Status() {
+		if st.State != PipelineRunning {
+			return fmt.Errorf("pipeline %s is %s: %s", st.Name, st.State, st.Error)
+		}
+	}
+	return probeReload()
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
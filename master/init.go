@@ -2,9 +2,10 @@ package master
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
-	"slices"
+	"sync"
 
 	"github.com/fufuok/pkg/common"
 	"github.com/fufuok/pkg/config"
@@ -19,76 +20,115 @@ var (
 
 	// 配置重载信息
 	reloadChan = make(chan bool)
+
+	// 程序退出或平滑重启时清理, 仅执行一次
+	stopPipelineOnce = sync.OnceFunc(stopPipeline)
 )
 
 // 注册常用助手函数
 func registerCommonFuncs() {
-	common.Funcs.Store("GetDataSource", config.GetDataSource)
+	common.Funcs.Store(config.MethodDataSource, config.GetDataSource)
+	common.Funcs.Store(config.MethodHTTPSource, config.GetHTTPSource)
+	common.Funcs.Store(config.MethodS3Source, config.GetS3Source)
+	common.Funcs.Store(config.MethodKVSource, config.GetKVSource)
+	common.Funcs.Store(config.MethodRedisSource, common.GetRedisSource)
 }
 
 // 注册框架级 Pipeline
 func registerPipeline() {
-	configPipelines = append([]Pipeline{&config.M{}, &common.M{}}, configPipelines...)
-	mainPipelines = append([]Pipeline{&crontab.M{}}, mainPipelines...)
-	mainPipelines = append(mainPipelines, &addons{})
+	mu.Lock()
+	defer mu.Unlock()
+	configPipelines = append([]*pipelineEntry{
+		newPipelineEntry(ConfigStage, "config", AdaptPipeline(&config.M{})),
+		newPipelineEntry(ConfigStage, "common", AdaptPipeline(&common.M{}), "config"),
+	}, configPipelines...)
+	mainPipelines = append([]*pipelineEntry{newPipelineEntry(MainStage, "crontab", AdaptPipeline(&crontab.M{}))}, mainPipelines...)
+	mainPipelines = append(mainPipelines, newPipelineEntry(MainStage, "addons", AdaptPipeline(&addons{})))
 }
 
 // 程序配置初始化入口
 func startConfigPipeline() {
-	ps := getPipelines(ConfigStage)
-	for _, p := range ps {
-		if err := p.Start(); err != nil {
-			log.Fatalln("Failed to initialize config:", err, "\nbye.")
-		}
+	if err := startStage(ConfigStage); err != nil {
+		log.Fatalln("Failed to initialize config:", err, "\nbye.")
 	}
 }
 
 // 程序初始化入口
 func startPipeline() {
-	ps := getPipelines(MainStage)
-	for _, p := range ps {
-		if err := p.Start(); err != nil {
-			log.Fatalln("Failed to initialize main:", err, "\nbye.")
-		}
+	if err := startStage(MainStage); err != nil {
+		log.Fatalln("Failed to initialize main:", err, "\nbye.")
 	}
+	logSecondarySecretKeys()
+
 	// 程序和配置监控
 	go mainScheduler()
+
+	// 平滑重启的新进程, 就绪后通知旧进程
+	go notifyGracefulReady()
 }
 
-// 配置变化时先加载新配置
-func runtimeConfigPipeline() {
-	ps := getPipelines(ConfigStage)
-	for _, p := range ps {
-		if err := p.Runtime(); err != nil {
-			alarm.Error().Err(err).Msg("Runtime config pipeline failed")
+// 按依赖顺序启动, 失败时逆序停止已启动的 Pipeline 并返回错误
+func startStage(stage Stage) error {
+	ps, err := getPipelines(stage)
+	if err != nil {
+		return err
+	}
+	for i, e := range ps {
+		if err := e.start(); err != nil {
+			return errors.Join(err, stopPipelines(ps[:i]))
 		}
 	}
+	return nil
+}
+
+// 配置变化时先加载新配置, 返回所有失败 Pipeline 的错误
+func runtimeConfigPipeline() error {
+	return runtimeStage(ConfigStage, "Runtime config pipeline failed")
+}
+
+// 配置变化时运行, 返回所有失败 Pipeline 的错误
+func runtimePipeline() error {
+	return runtimeStage(MainStage, "Runtime main pipeline failed")
 }
 
-// 配置变化时运行
-func runtimePipeline() {
-	ps := getPipelines(MainStage)
-	for _, p := range ps {
-		if err := p.Runtime(); err != nil {
-			alarm.Error().Err(err).Msg("Runtime main pipeline failed")
+func runtimeStage(stage Stage, msg string) error {
+	ps, err := getPipelines(stage)
+	if err != nil {
+		alarm.Error().Err(err).Msg(msg)
+		return err
+	}
+	var errs []error
+	diff := config.LastDiff()
+	for _, e := range ps {
+		if err := e.runtime(diff); err != nil {
+			alarm.Error().Err(err).Msg(msg)
+			errs = append(errs, err)
 		}
 	}
+	return errors.Join(errs...)
 }
 
-// 程序退出时清理
+// 程序退出时清理, 逆序停止所有 Pipeline, 记录所有失败 Pipeline 的错误
 func stopPipeline() {
-	ps := append(getPipelines(ConfigStage), getPipelines(MainStage)...)
-	slices.Reverse(ps)
-	for _, p := range ps {
-		if err := p.Stop(); err != nil {
-			logger.Fatal().Err(err).Str("app", config.AppName).Msg("Main exited")
+	var ps []*pipelineEntry
+	for _, stage := range []Stage{ConfigStage, MainStage} {
+		sorted, err := getPipelines(stage)
+		if err != nil {
+			sorted = getRegisteredPipelines(stage)
 		}
+		ps = append(ps, sorted...)
+	}
+	if err := stopPipelines(ps); err != nil {
+		logger.Error().Err(err).Str("app", config.AppName).Msg("Main exited with errors")
+		return
 	}
 	logger.Warn().Str("app", config.AppName).Msg("Main exited")
 }
 
 func mainScheduler() {
 	initWatcher()
+	// 新版本观察期健康检查
+	go startRollout()
 	for {
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -97,8 +137,22 @@ func mainScheduler() {
 
 		select {
 		case <-restartChan:
-			// 强制退出, 由 Daemon 重启程序
 			logger.Warn().Msg("Restart <-restartChan")
+			if config.Config().SYSConf.GracefulRestart {
+				// 平滑重启, 新进程接管服务, 旧进程处理完已有请求后退出
+				err := gracefulRestart(cancel)
+				if err == nil {
+					os.Exit(0)
+				}
+				if errors.Is(err, errGracefulNotReady) {
+					// 新进程未就绪, 旧进程继续提供服务
+					alarm.Error().Err(err).Msg("Graceful restart failed, keep running")
+					cancel()
+					continue
+				}
+				logger.Error().Err(err).Msg("Graceful restart failed")
+			}
+			// 强制退出, 由 Daemon 重启程序
 			os.Exit(0)
 		case <-reloadChan:
 			// 重载配置及相关服务
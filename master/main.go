@@ -50,6 +50,12 @@ func Run() {
 	// 手动设置 > 1, 避免 CPU 隔离时协程池调度可能的阻塞
 	runtime.GOMAXPROCS(config.DefaultGOMAXPROCS)
 
+	// 收到退出信号时取消运行中的 Pipeline.Start()/Runtime(), 如启动阶段卡住的 Start()
+	go func() {
+		utils.WaitSignal()
+		runCancel()
+	}()
+
 	Start()
 	defer Stop()
 
@@ -66,5 +72,6 @@ func Start() {
 
 // Stop 程序退出
 func Stop() {
-	stopPipeline()
+	runCancel()
+	stopPipelineOnce()
 }
@@ -0,0 +1,149 @@
+package master
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fufuok/pkg/logger"
+)
+
+var (
+	// WatcherNotify 是否启用文件事件监控 (Linux inotify), 文件变化后即时检查, 不可用时仅定时轮询
+	WatcherNotify = true
+
+	// WatcherDebounce 文件事件合并时间, 最后一个事件后该时间内无新事件时再检查文件内容
+	WatcherDebounce = 500 * time.Millisecond
+
+	// WatcherRescanInterval 启用文件事件监控时, 定时全量检查文件内容的间隔, 防止遗漏事件 (如网络文件系统)
+	WatcherRescanInterval = 30 * time.Minute
+
+	// 文件事件监控, 未启用或不可用时为 nil
+	notifier fileNotifier
+)
+
+// 事件队列溢出 (可能丢失事件) 时发送的路径, 触发全量检查
+const notifyOverflow = ""
+
+// 文件事件监控后端
+type fileNotifier interface {
+	// Watch 监控目录 (不含子目录), 目录不存在时忽略, 由定时全量检查兜底
+	Watch(dirs ...string) error
+	// Events 目录内有变化的文件路径, 事件丢失时为 notifyOverflow
+	Events() <-chan string
+	// Rewatch 重新监控被删除或移动后又出现的目录, 有目录恢复监控时返回 true
+	Rewatch() bool
+	Close() error
+}
+
+// 监控器检查范围: 全量检查, 或仅检查有文件事件的目录下的文件
+type changedFiles struct {
+	all  bool
+	dirs map[string]struct{}
+}
+
+// 文件列表中是否有文件可能变化, 需重新计算 Hash
+func (c changedFiles) has(files ...string) bool {
+	if c.all {
+		return true
+	}
+	for _, f := range files {
+		if _, ok := c.dirs[cleanDir(f)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 启动文件事件监控, 返回合并后的变化目录集合, 不可用时返回 nil
+func startFileNotifier(dirs ...string) <-chan map[string]struct{} {
+	if !WatcherNotify {
+		return nil
+	}
+	n, err := newFileNotifier()
+	if err != nil {
+		logger.Warn().Err(err).Msg("File notify unavailable, polling only")
+		return nil
+	}
+	if err := n.Watch(dirs...); err != nil {
+		logger.Warn().Err(err).Msg("File notify unavailable, polling only")
+		_ = n.Close()
+		return nil
+	}
+	notifier = n
+	out := make(chan map[string]struct{})
+	go debounceEvents(n.Events(), WatcherDebounce, out)
+	return out
+}
+
+// 监控文件所在目录, 兼容改名替换方式的写入 (如编辑器, dpkg, Kubernetes ConfigMap)
+func watchFiles(files ...string) {
+	dirs := make([]string, 0, len(files))
+	for _, f := range files {
+		if f != "" {
+			dirs = append(dirs, cleanDir(f))
+		}
+	}
+	watchDirs(dirs...)
+}
+
+// 监控新增的目录 (配置重载后的配置文件列表, 运行时添加的 Watcher)
+func watchDirs(dirs ...string) {
+	if notifier == nil {
+		return
+	}
+	if err := notifier.Watch(dirs...); err != nil {
+		logger.Warn().Err(err).Strs("dirs", dirs).Msg("Failed to watch dirs")
+	}
+}
+
+// 重新监控被删除后又重新创建的目录, 有目录恢复监控时返回 true, 期间的变化需全量检查
+func rewatchDirs() bool {
+	return notifier != nil && notifier.Rewatch()
+}
+
+// 合并文件事件: 最后一个事件后 wait 时间内无新事件时, 发送有变化的目录集合
+// 接收方忙时继续合并, 直到被接收
+func debounceEvents(events <-chan string, wait time.Duration, out chan<- map[string]struct{}) {
+	var (
+		pending map[string]struct{}
+		send    chan<- map[string]struct{}
+	)
+	timer := time.NewTimer(wait)
+	timer.Stop()
+	for {
+		select {
+		case name, ok := <-events:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			if pending == nil {
+				pending = make(map[string]struct{})
+			}
+			if name == notifyOverflow {
+				pending[notifyOverflow] = struct{}{}
+			} else {
+				pending[cleanDir(name)] = struct{}{}
+			}
+			send = nil
+			timer.Reset(wait)
+		case <-timer.C:
+			send = out
+		case send <- pending:
+			pending = nil
+			send = nil
+		}
+	}
+}
+
+// 文件所在目录的绝对路径
+func cleanDir(name string) string {
+	return filepath.Dir(absPath(name))
+}
+
+func absPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return filepath.Clean(name)
+}
@@ -0,0 +1,156 @@
+//go:build linux
+
+package master
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotify 文件事件监控
+type inotifyNotifier struct {
+	fd     int
+	f      *os.File
+	events chan string
+
+	mu   sync.Mutex
+	dirs map[string]int
+	wds  map[int]string
+	// 被删除或移动 (IN_IGNORED) 的目录, 由 Rewatch 重新监控
+	ignored map[string]struct{}
+}
+
+func newFileNotifier() (fileNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &inotifyNotifier{
+		fd: fd,
+		// 非阻塞文件描述符由运行时网络轮询器管理, Close 时 Read 立即返回
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		events:  make(chan string, 128),
+		dirs:    make(map[string]int),
+		wds:     make(map[int]string),
+		ignored: make(map[string]struct{}),
+	}
+	go n.readEvents()
+	return n, nil
+}
+
+func (n *inotifyNotifier) Watch(dirs ...string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var errs []error
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		dir = absPath(dir)
+		if _, ok := n.dirs[dir]; ok {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+		if err != nil {
+			errs = append(errs, &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err})
+			continue
+		}
+		n.dirs[dir] = wd
+		n.wds[wd] = dir
+	}
+	return errors.Join(errs...)
+}
+
+func (n *inotifyNotifier) Events() <-chan string {
+	return n.events
+}
+
+func (n *inotifyNotifier) Rewatch() bool {
+	n.mu.Lock()
+	dirs := make([]string, 0, len(n.ignored))
+	for dir := range n.ignored {
+		dirs = append(dirs, dir)
+	}
+	n.mu.Unlock()
+	if len(dirs) == 0 {
+		return false
+	}
+
+	_ = n.Watch(dirs...)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	restored := false
+	for _, dir := range dirs {
+		if _, ok := n.dirs[dir]; ok {
+			delete(n.ignored, dir)
+			restored = true
+		}
+	}
+	return restored
+}
+
+func (n *inotifyNotifier) Close() error {
+	return n.f.Close()
+}
+
+func (n *inotifyNotifier) readEvents() {
+	defer close(n.events)
+	buf := make([]byte, 64*1024)
+	for {
+		size, err := n.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= size; {
+			wd := int(int32(binary.NativeEndian.Uint32(buf[off:])))
+			mask := binary.NativeEndian.Uint32(buf[off+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[off+12:]))
+			start := off + syscall.SizeofInotifyEvent
+			off = start + nameLen
+			if off > size {
+				break
+			}
+			name := string(trimNull(buf[start:off]))
+
+			// 事件队列溢出, 之前的事件可能丢失
+			if wd == -1 && mask&syscall.IN_Q_OVERFLOW != 0 {
+				n.events <- notifyOverflow
+				continue
+			}
+
+			n.mu.Lock()
+			dir, ok := n.wds[wd]
+			if ok && mask&syscall.IN_IGNORED != 0 {
+				// 目录被删除或移动, 重新创建后由 Rewatch 再次添加
+				delete(n.wds, wd)
+				delete(n.dirs, dir)
+				n.ignored[dir] = struct{}{}
+			}
+			n.mu.Unlock()
+			// 目录自身的事件忽略, 由定时全量检查兜底
+			if !ok || name == "" {
+				continue
+			}
+			n.events <- filepath.Join(dir, name)
+		}
+	}
+}
+
+func trimNull(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
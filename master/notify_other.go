@@ -0,0 +1,11 @@
+//go:build !linux
+
+package master
+
+import (
+	"errors"
+)
+
+func newFileNotifier() (fileNotifier, error) {
+	return nil, errors.New("file notify is not supported on this platform")
+}
@@ -0,0 +1,103 @@
+package master
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestDebounceEvents(t *testing.T) {
+	events := make(chan string)
+	out := make(chan map[string]struct{})
+	go debounceEvents(events, 50*time.Millisecond, out)
+	defer close(events)
+
+	events <- "/etc/app/a.json"
+	events <- "/etc/app/b.json"
+	events <- "/opt/app/bin/app"
+	select {
+	case dirs := <-out:
+		assert.Equal(t, 2, len(dirs))
+		changed := changedFiles{dirs: dirs}
+		assert.True(t, changed.has("/etc/app/c.json"))
+		assert.True(t, changed.has("/var/none", "/opt/app/bin/app"))
+		assert.False(t, changed.has("/var/none"))
+	case <-time.After(time.Second):
+		t.Fatal("debounced events not received")
+	}
+
+	// 事件队列溢出
+	events <- notifyOverflow
+	select {
+	case dirs := <-out:
+		_, ok := dirs[notifyOverflow]
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("overflow event not received")
+	}
+	assert.True(t, changedFiles{all: true}.has())
+	assert.False(t, changedFiles{}.has("/etc/app/a.json"))
+}
+
+func TestFileNotifier(t *testing.T) {
+	n, err := newFileNotifier()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer func() {
+		_ = n.Close()
+	}()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	assert.Nil(t, n.Watch(dir, filepath.Join(dir, "none")))
+
+	// 改名替换方式写入
+	tmp := filepath.Join(dir, "app.json.tmp")
+	assert.Nil(t, os.WriteFile(tmp, []byte("{}"), 0o600))
+	assert.Nil(t, os.Rename(tmp, file))
+	for {
+		select {
+		case name := <-n.Events():
+			if name == file {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("file event not received")
+		}
+	}
+}
+
+func TestFileNotifierRewatch(t *testing.T) {
+	n, err := newFileNotifier()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer func() {
+		_ = n.Close()
+	}()
+	go func() {
+		for range n.Events() {
+		}
+	}()
+
+	dir := filepath.Join(t.TempDir(), "conf")
+	assert.Nil(t, os.Mkdir(dir, 0o755))
+	assert.Nil(t, n.Watch(dir))
+	assert.False(t, n.Rewatch())
+
+	// 目录被删除后重新创建, 再次监控
+	assert.Nil(t, os.Remove(dir))
+	assert.Nil(t, os.Mkdir(dir, 0o755))
+	deadline := time.Now().Add(2 * time.Second)
+	for !n.Rewatch() {
+		if time.Now().After(deadline) {
+			t.Fatal("removed dir not rewatched")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.False(t, n.Rewatch())
+}
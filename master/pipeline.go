@@ -2,8 +2,14 @@ package master
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"slices"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/fufuok/pkg/config"
 )
 
 const (
@@ -17,10 +23,32 @@ const (
 	RemoteStage
 )
 
+// Pipeline 运行状态
+const (
+	PipelineRegistered = "registered"
+	PipelineStarting   = "starting"
+	PipelineRunning    = "running"
+	PipelineFailed     = "failed"
+	PipelineStopping   = "stopping"
+	PipelineStopped    = "stopped"
+)
+
 var (
+	// PipelineStartTimeout 单个 Pipeline.Start() 超时时间, 0 为不限制
+	PipelineStartTimeout = 2 * time.Minute
+
+	// PipelineRuntimeTimeout 单个 Pipeline.Runtime() 超时时间, 0 为不限制
+	PipelineRuntimeTimeout = time.Minute
+
+	// PipelineStopTimeout 单个 Pipeline.Stop() 超时时间, 0 为不限制
+	PipelineStopTimeout = 30 * time.Second
+
+	// 程序退出时取消, 运行中的 Start()/Runtime() 收到取消信号
+	runCtx, runCancel = context.WithCancel(context.Background())
+
 	mu              sync.Mutex
-	configPipelines []Pipeline
-	mainPipelines   []Pipeline
+	configPipelines []*pipelineEntry
+	mainPipelines   []*pipelineEntry
 	remotePipelines []ContextFunc
 )
 
@@ -32,20 +60,107 @@ type (
 		Runtime() error
 		Stop() error
 	}
+
+	// PipelineCtx 可取消的 Pipeline, ctx 带有阶段超时 (PipelineStartTimeout 等),
+	// Start/Runtime 的 ctx 在程序退出时取消, diff 为本次配置热加载的差异
+	PipelineCtx interface {
+		Start(ctx context.Context) error
+		Runtime(ctx context.Context, diff *config.ConfigDiff) error
+		Stop(ctx context.Context) error
+	}
 )
 
-func Register(stage Stage, sf ...Pipeline) {
-	mu.Lock()
-	defer mu.Unlock()
-	switch stage {
+// 将 Pipeline 适配为 PipelineCtx, 超时或取消时不再等待原方法返回
+type pipelineAdapter struct {
+	p Pipeline
+}
+
+func (a pipelineAdapter) Start(context.Context) error {
+	return a.p.Start()
+}
+
+func (a pipelineAdapter) Runtime(context.Context, *config.ConfigDiff) error {
+	return a.p.Runtime()
+}
+
+func (a pipelineAdapter) Stop(context.Context) error {
+	return a.p.Stop()
+}
+
+// AdaptPipeline 将 Pipeline 适配为 PipelineCtx
+func AdaptPipeline(p Pipeline) PipelineCtx {
+	return pipelineAdapter{p: p}
+}
+
+// PipelineStatus Pipeline 运行状态
+type PipelineStatus struct {
+	Name      string        `json:"name"`
+	Stage     string        `json:"stage"`
+	DependsOn []string      `json:"depends_on"`
+	State     string        `json:"state"`
+	Error     string        `json:"error"`
+	StartedAt time.Time     `json:"started_at"`
+	Runtimes  uint64        `json:"runtimes"`
+	Took      time.Duration `json:"took"`
+}
+
+type pipelineEntry struct {
+	name string
+	deps []string
+	p    PipelineCtx
+
+	// 执行中的方法, 超时后仍在后台运行的方法结束前不再执行新的方法
+	inflight chan struct{}
+
+	mu     sync.Mutex
+	status PipelineStatus
+}
+
+func (s Stage) String() string {
+	switch s {
 	case ConfigStage:
-		configPipelines = append(configPipelines, sf...)
+		return "config"
 	case MainStage:
-		mainPipelines = append(mainPipelines, sf...)
+		return "main"
 	case RemoteStage:
+		return "remote"
+	default:
+		return fmt.Sprintf("stage(%d)", int(s))
+	}
+}
+
+// Register 按注册顺序添加 Pipeline, 名称为类型名, 如: *app.M
+// 同类型重复注册时名称追加序号, 如: *app.M#2, 依赖其他 Pipeline 或被依赖时使用 RegisterNamed
+func Register(stage Stage, sf ...Pipeline) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range sf {
+		addPipeline(stage, newPipelineEntry(stage, anonymousName(p), AdaptPipeline(p)))
 	}
 }
 
+// RegisterNamed 添加命名 Pipeline, 名称不能重复, 在 dependsOn 中的 Pipeline 启动后启动, 在其停止前停止
+// 依赖可以是同阶段或 ConfigStage 中的 Pipeline, 框架内置: config, common, crontab, addons
+func RegisterNamed(stage Stage, name string, p Pipeline, dependsOn ...string) {
+	RegisterNamedCtx(stage, name, AdaptPipeline(p), dependsOn...)
+}
+
+// RegisterCtx 按注册顺序添加 PipelineCtx, 名称同 Register
+func RegisterCtx(stage Stage, sf ...PipelineCtx) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range sf {
+		addPipeline(stage, newPipelineEntry(stage, anonymousName(p), p))
+	}
+}
+
+// RegisterNamedCtx 添加命名 PipelineCtx, 依赖关系同 RegisterNamed
+func RegisterNamedCtx(stage Stage, name string, p PipelineCtx, dependsOn ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	addPipeline(stage, newPipelineEntry(stage, name, p, dependsOn...))
+}
+
 func RegisterWithContext(stage Stage, sf ...ContextFunc) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -57,7 +172,70 @@ func RegisterWithContext(stage Stage, sf ...ContextFunc) {
 	}
 }
 
-func getPipelines(stage Stage) (ps []Pipeline) {
+// Status 所有 Pipeline 的运行状态, 按启动顺序
+func Status() []PipelineStatus {
+	var res []PipelineStatus
+	for _, stage := range []Stage{ConfigStage, MainStage} {
+		ps, err := getPipelines(stage)
+		if err != nil {
+			ps = getRegisteredPipelines(stage)
+		}
+		for _, e := range ps {
+			res = append(res, e.getStatus())
+		}
+	}
+	return res
+}
+
+func newPipelineEntry(stage Stage, name string, p PipelineCtx, deps ...string) *pipelineEntry {
+	return &pipelineEntry{
+		name:     name,
+		deps:     slices.Clone(deps),
+		p:        p,
+		inflight: make(chan struct{}, 1),
+		status: PipelineStatus{
+			Name:      name,
+			Stage:     stage.String(),
+			DependsOn: slices.Clone(deps),
+			State:     PipelineRegistered,
+		},
+	}
+}
+
+func typeName(p any) string {
+	return fmt.Sprintf("%T", p)
+}
+
+// 匿名注册的 Pipeline 名称: 类型名, 已被使用时追加序号, 调用方持有 mu
+func anonymousName(p any) string {
+	name := typeName(p)
+	used := func(name string) bool {
+		has := func(e *pipelineEntry) bool { return e.name == name }
+		return slices.ContainsFunc(configPipelines, has) || slices.ContainsFunc(mainPipelines, has)
+	}
+	if !used(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		if n := name + "#" + strconv.Itoa(i); !used(n) {
+			return n
+		}
+	}
+}
+
+func addPipeline(stage Stage, e *pipelineEntry) {
+	switch stage {
+	case ConfigStage:
+		configPipelines = append(configPipelines, e)
+	case MainStage:
+		mainPipelines = append(mainPipelines, e)
+	case RemoteStage:
+	}
+}
+
+func getRegisteredPipelines(stage Stage) (ps []*pipelineEntry) {
+	mu.Lock()
+	defer mu.Unlock()
 	switch stage {
 	case ConfigStage:
 		ps = configPipelines
@@ -65,11 +243,181 @@ func getPipelines(stage Stage) (ps []Pipeline) {
 		ps = mainPipelines
 	case RemoteStage:
 	}
+	return slices.Clone(ps)
+}
 
-	mu.Lock()
-	ps = slices.Clone(ps)
-	mu.Unlock()
-	return
+// 按依赖关系排序 (拓扑排序), 无依赖关系的 Pipeline 保持注册顺序
+func getPipelines(stage Stage) ([]*pipelineEntry, error) {
+	ps := getRegisteredPipelines(stage)
+	var earlier []*pipelineEntry
+	if stage == MainStage {
+		earlier = getRegisteredPipelines(ConfigStage)
+	}
+	return sortPipelines(ps, earlier)
+}
+
+func sortPipelines(ps, earlier []*pipelineEntry) ([]*pipelineEntry, error) {
+	known := make(map[string]bool, len(ps)+len(earlier))
+	for _, e := range earlier {
+		known[e.name] = true
+	}
+	pending := make(map[string]bool, len(ps))
+	for _, e := range ps {
+		if pending[e.name] || known[e.name] {
+			return nil, fmt.Errorf("duplicate pipeline: %s", e.name)
+		}
+		pending[e.name] = true
+	}
+	for _, e := range ps {
+		for _, dep := range e.deps {
+			if !pending[dep] && !known[dep] {
+				return nil, fmt.Errorf("pipeline %s depends on unknown pipeline: %s", e.name, dep)
+			}
+		}
+	}
+
+	sorted := make([]*pipelineEntry, 0, len(ps))
+	for len(ps) > 0 {
+		i := slices.IndexFunc(ps, func(e *pipelineEntry) bool {
+			return !slices.ContainsFunc(e.deps, func(dep string) bool { return pending[dep] })
+		})
+		if i < 0 {
+			names := make([]string, len(ps))
+			for j, e := range ps {
+				names[j] = e.name
+			}
+			return nil, fmt.Errorf("pipeline dependency cycle: %v", names)
+		}
+		e := ps[i]
+		delete(pending, e.name)
+		sorted = append(sorted, e)
+		ps = slices.Delete(ps, i, i+1)
+	}
+	return sorted, nil
+}
+
+func (e *pipelineEntry) getStatus() PipelineStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st := e.status
+	st.DependsOn = slices.Clone(st.DependsOn)
+	return st
+}
+
+func (e *pipelineEntry) setState(state string) {
+	e.mu.Lock()
+	e.status.State = state
+	e.mu.Unlock()
+}
+
+func (e *pipelineEntry) start() error {
+	e.setState(PipelineStarting)
+	start := time.Now()
+	err := e.runPhase(runCtx, "start", PipelineStartTimeout, e.p.Start)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status.Took = time.Since(start)
+	e.status.Error = errorString(err)
+	if err != nil {
+		e.status.State = PipelineFailed
+		return err
+	}
+	e.status.State = PipelineRunning
+	e.status.StartedAt = start
+	return nil
+}
+
+func (e *pipelineEntry) runtime(diff *config.ConfigDiff) error {
+	start := time.Now()
+	err := e.runPhase(runCtx, "runtime", PipelineRuntimeTimeout, func(ctx context.Context) error {
+		return e.p.Runtime(ctx, diff)
+	})
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status.Runtimes++
+	e.status.Took = time.Since(start)
+	e.status.Error = errorString(err)
+	return err
+}
+
+func (e *pipelineEntry) stop() error {
+	e.mu.Lock()
+	state := e.status.State
+	e.mu.Unlock()
+	if state == PipelineRegistered || state == PipelineStopped {
+		return nil
+	}
+
+	e.setState(PipelineStopping)
+	start := time.Now()
+	// 程序退出时 runCtx 已取消, Stop 仅受超时限制
+	err := e.runPhase(context.Background(), "stop", PipelineStopTimeout, e.p.Stop)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status.Took = time.Since(start)
+	e.status.Error = errorString(err)
+	e.status.State = PipelineStopped
+	if err != nil {
+		e.status.State = PipelineFailed
+	}
+	return err
+}
+
+// 执行 Pipeline 方法, 超时或取消后返回错误 (不响应 ctx 的方法仍在后台运行)
+// 同一 Pipeline 的方法串行执行, 上一次调用仍在后台运行时等待其结束, 等待同样受超时限制
+// 方法崩溃时返回错误, 不影响其他 Pipeline
+func (e *pipelineEntry) runPhase(parent context.Context, phase string, timeout time.Duration,
+	fn func(ctx context.Context) error,
+) error {
+	ctx, cancel := parent, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	}
+	defer cancel()
+
+	select {
+	case e.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("pipeline %s %s: previous call still running: %w", e.name, phase, ctx.Err())
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+			<-e.inflight
+		}()
+		done <- fn(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("pipeline %s %s: %w", e.name, phase, err)
+	}
+	return nil
+}
+
+// 逆序停止, 返回所有错误
+func stopPipelines(ps []*pipelineEntry) error {
+	var errs []error
+	for _, e := range slices.Backward(ps) {
+		if err := e.stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
 }
 
 func getPipelinesWithContext(stage Stage) (ps []ContextFunc) {
@@ -0,0 +1,182 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/config"
+)
+
+type testPipeline struct {
+	name  string
+	calls *[]string
+	err   error
+	sleep time.Duration
+}
+
+func (p *testPipeline) Start() error {
+	*p.calls = append(*p.calls, "start:"+p.name)
+	time.Sleep(p.sleep)
+	return p.err
+}
+
+func (p *testPipeline) Runtime() error {
+	return nil
+}
+
+func (p *testPipeline) Stop() error {
+	*p.calls = append(*p.calls, "stop:"+p.name)
+	return p.err
+}
+
+func TestSortPipelines(t *testing.T) {
+	var calls []string
+	entry := func(name string, deps ...string) *pipelineEntry {
+		return newPipelineEntry(MainStage, name, AdaptPipeline(&testPipeline{name: name, calls: &calls}), deps...)
+	}
+	earlier := []*pipelineEntry{entry("config")}
+
+	ps, err := sortPipelines([]*pipelineEntry{
+		entry("web", "db", "cache"),
+		entry("crontab"),
+		entry("cache", "config"),
+		entry("db"),
+	}, earlier)
+	assert.Nil(t, err)
+	var names []string
+	for _, e := range ps {
+		names = append(names, e.name)
+	}
+	assert.Equal(t, []string{"crontab", "cache", "db", "web"}, names)
+
+	_, err = sortPipelines([]*pipelineEntry{entry("a", "b"), entry("b", "a")}, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, "cycle", err.Error())
+	_, err = sortPipelines([]*pipelineEntry{entry("a", "none")}, nil)
+	assert.Contains(t, "unknown pipeline: none", err.Error())
+	_, err = sortPipelines([]*pipelineEntry{entry("config")}, earlier)
+	assert.Contains(t, "duplicate pipeline: config", err.Error())
+	assert.Equal(t, "*master.testPipeline", typeName(&testPipeline{}))
+}
+
+func TestRegisterAnonymous(t *testing.T) {
+	mu.Lock()
+	old := mainPipelines
+	mainPipelines = nil
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		mainPipelines = old
+		mu.Unlock()
+	}()
+
+	var calls []string
+	Register(MainStage, &testPipeline{calls: &calls}, &testPipeline{calls: &calls})
+	RegisterCtx(MainStage, AdaptPipeline(&testPipeline{calls: &calls}))
+	ps, err := getPipelines(MainStage)
+	assert.Nil(t, err)
+	var names []string
+	for _, e := range ps {
+		names = append(names, e.name)
+	}
+	assert.Equal(t, []string{"*master.testPipeline", "*master.testPipeline#2", "master.pipelineAdapter"}, names)
+}
+
+func TestPipelineLifecycle(t *testing.T) {
+	var calls []string
+	bad := errors.New("bad")
+	ps := []*pipelineEntry{
+		newPipelineEntry(MainStage, "a", AdaptPipeline(&testPipeline{name: "a", calls: &calls})),
+		newPipelineEntry(MainStage, "b", AdaptPipeline(&testPipeline{name: "b", calls: &calls, err: bad})),
+		newPipelineEntry(MainStage, "c", AdaptPipeline(&testPipeline{name: "c", calls: &calls})),
+	}
+	for _, e := range ps[:2] {
+		_ = e.start()
+	}
+	assert.Equal(t, PipelineRunning, ps[0].getStatus().State)
+	st := ps[1].getStatus()
+	assert.Equal(t, PipelineFailed, st.State)
+	assert.Equal(t, "pipeline b start: bad", st.Error)
+	assert.Equal(t, PipelineRegistered, ps[2].getStatus().State)
+
+	// 逆序停止, 未启动的忽略, 错误全部返回
+	calls = nil
+	err := stopPipelines(ps)
+	assert.True(t, errors.Is(err, bad))
+	assert.Equal(t, []string{"stop:b", "stop:a"}, calls)
+	assert.Equal(t, PipelineStopped, ps[0].getStatus().State)
+
+	// 超时
+	timeout := PipelineStartTimeout
+	PipelineStartTimeout = 10 * time.Millisecond
+	defer func() {
+		PipelineStartTimeout = timeout
+	}()
+	slow := newPipelineEntry(MainStage, "slow", AdaptPipeline(&testPipeline{name: "slow", calls: new([]string), sleep: time.Second}))
+	err = slow.start()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, "pipeline slow start", err.Error())
+
+	// 超时后仍在运行的方法结束前, 不再执行新的方法
+	calls = nil
+	slow = newPipelineEntry(MainStage, "slow", AdaptPipeline(&testPipeline{name: "slow", calls: &calls, sleep: 100 * time.Millisecond}))
+	err = slow.start()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	err = slow.start()
+	assert.Contains(t, "previous call still running", err.Error())
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, slow.stop())
+	assert.Equal(t, []string{"start:slow", "stop:slow"}, calls)
+}
+
+type testCtxPipeline struct {
+	diff *config.ConfigDiff
+}
+
+func (p *testCtxPipeline) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *testCtxPipeline) Runtime(_ context.Context, diff *config.ConfigDiff) error {
+	p.diff = diff
+	return nil
+}
+
+func (p *testCtxPipeline) Stop(context.Context) error {
+	return nil
+}
+
+func TestPipelineCtx(t *testing.T) {
+	p := &testCtxPipeline{}
+	e := newPipelineEntry(MainStage, typeName(p), p)
+	diff := &config.ConfigDiff{Changes: []config.Change{{Path: "log_conf.level"}}}
+	assert.Nil(t, e.runtime(diff))
+	assert.True(t, p.diff.Changed("log_conf"))
+	assert.Equal(t, uint64(1), e.getStatus().Runtimes)
+
+	// 取消时 Start 返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := e.runPhase(ctx, "start", time.Minute, p.Start)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+type testPanicPipeline struct{}
+
+func (testPanicPipeline) Start() error   { panic("boom") }
+func (testPanicPipeline) Runtime() error { return nil }
+func (testPanicPipeline) Stop() error    { return nil }
+
+func TestPipelinePanic(t *testing.T) {
+	e := newPipelineEntry(MainStage, "panic", AdaptPipeline(testPanicPipeline{}))
+	err := e.start()
+	assert.NotNil(t, err)
+	assert.Contains(t, "pipeline panic start: panic: boom", err.Error())
+	assert.Equal(t, PipelineFailed, e.getStatus().State)
+	assert.Contains(t, "boom", e.getStatus().Error)
+}
@@ -0,0 +1,192 @@
+package master
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/logger"
+	"github.com/fufuok/pkg/logger/sampler"
+)
+
+// PushSubscriber 远端配置推送订阅, 收到配置变更通知时调用 notify
+// 阻塞运行直到 ctx 结束 (返回 nil) 或连接断开 (返回错误, 稍后自动重新订阅)
+type PushSubscriber func(ctx context.Context, cfg config.FilesConf, notify func()) error
+
+var (
+	// PushRetryMinInterval 推送订阅断开后重新订阅的最小间隔, 失败时逐次翻倍
+	PushRetryMinInterval = time.Second
+
+	// PushRetryMaxInterval 推送订阅断开后重新订阅的最大间隔
+	PushRetryMaxInterval = time.Minute
+
+	pushSubscribersMu sync.RWMutex
+	pushSubscribers   = map[string]PushSubscriber{
+		"http":  subscribeHTTP,
+		"https": subscribeHTTP,
+		"redis": subscribeRedis,
+	}
+)
+
+// RegisterPushSubscriber 注册或替换推送订阅协议, scheme 对应 FilesConf.Push 地址的协议, 如: ws, wss, nats
+func RegisterPushSubscriber(scheme string, fn PushSubscriber) {
+	pushSubscribersMu.Lock()
+	pushSubscribers[strings.ToLower(scheme)] = fn
+	pushSubscribersMu.Unlock()
+}
+
+func getPushSubscriber(push string) (PushSubscriber, error) {
+	scheme, _, ok := strings.Cut(push, "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid push address: %s", push)
+	}
+	pushSubscribersMu.RLock()
+	fn, ok := pushSubscribers[strings.ToLower(scheme)]
+	pushSubscribersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown push scheme: %s", scheme)
+	}
+	return fn, nil
+}
+
+// 订阅配置推送, 收到通知时写入 trigger, 由 GetRemoteConf 立即获取配置
+func startPushSubscriber(ctx context.Context, id string, cfg config.FilesConf, trigger chan<- struct{}) {
+	subscribe, err := getPushSubscriber(cfg.Push)
+	if err != nil {
+		logger.Error().Err(err).Str("id", id).Str("path", cfg.Path).Msg("Remote config push disabled")
+		return
+	}
+	notify := func() {
+		notifyTrigger(trigger)
+	}
+	go func() {
+		logger.Warn().Str("id", id).Str("path", cfg.Path).Str("push", cfg.Push).
+			Msg("Remote config push subscriber started")
+		retry := PushRetryMinInterval
+		for {
+			start := time.Now()
+			err := subscribe(ctx, cfg, notify)
+			select {
+			case <-ctx.Done():
+				logger.Warn().Str("id", id).Str("path", cfg.Path).Str("push", cfg.Push).
+					Msg("Remote config push subscriber exited")
+				return
+			default:
+			}
+			// 订阅正常运行过一段时间后断开, 重置重试间隔
+			if time.Since(start) > PushRetryMaxInterval {
+				retry = PushRetryMinInterval
+			}
+			sampler.Warn().Err(err).Str("id", id).Str("path", cfg.Path).Str("push", cfg.Push).
+				Dur("retry", retry).Msg("Remote config push subscriber disconnected")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, PushRetryMaxInterval)
+		}
+	}()
+}
+
+// HTTP 推送订阅: 响应为 text/event-stream 时按 SSE 处理, 每个事件触发一次通知
+// 否则为长轮询: 200 触发通知, 204/304/408 表示无变化, 立即发起下一次请求
+func subscribeHTTP(ctx context.Context, cfg config.FilesConf, notify func()) error {
+	for {
+		start := time.Now()
+		r := common.ReqDownload.R().SetContext(ctx).DisableAutoReadResponse()
+		if err := config.SetSourceRequest(r, cfg); err != nil {
+			return err
+		}
+		resp, err := r.Get(cfg.Push)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(resp.GetContentType(), "text/event-stream") {
+			return readSSE(resp.Response, notify)
+		}
+		_ = resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK:
+			notify()
+		case http.StatusNoContent, http.StatusNotModified, http.StatusRequestTimeout:
+		default:
+			return fmt.Errorf("push request failed: [%d] %s", resp.StatusCode, resp.Status)
+		}
+		// 服务端未挂起请求时, 限制请求频率
+		if wait := PushRetryMinInterval - time.Since(start); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// 读取 SSE 事件流, 每个包含 data 的事件 (空行结束) 触发一次通知
+func readSSE(resp *http.Response, notify func()) error {
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("push request failed: [%d] %s", resp.StatusCode, resp.Status)
+	}
+	hasData := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if hasData {
+				notify()
+			}
+			hasData = false
+		case strings.HasPrefix(line, "data:"):
+			hasData = true
+		default:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("push event stream closed")
+}
+
+// Redis 发布订阅: redis://{channel}, 每条消息触发一次通知
+func subscribeRedis(ctx context.Context, cfg config.FilesConf, notify func()) error {
+	if !common.RedisDBInited.Load() {
+		return errors.New("redis push: RedisDB is not initialized")
+	}
+	_, channel, _ := strings.Cut(cfg.Push, "://")
+	if channel == "" {
+		return fmt.Errorf("redis push channel cannot be empty: %s", cfg.Push)
+	}
+
+	sub := common.RedisDB.Subscribe(ctx, channel)
+	defer func() {
+		_ = sub.Close()
+	}()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-ch:
+			if !ok {
+				return errors.New("redis push channel closed")
+			}
+			notify()
+		}
+	}
+}
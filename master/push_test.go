@@ -0,0 +1,78 @@
+package master
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/imroc/req/v3"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+)
+
+func TestSubscribeHTTP(t *testing.T) {
+	if common.ReqDownload == nil {
+		common.ReqDownload = req.C()
+	}
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/sse":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(": ping\n\nevent: change\ndata: app.json\n\ndata: 1\ndata: 2\n\n"))
+		case "/poll":
+			// 第 1 次无变化, 第 2 次有变化, 之后挂起直到客户端断开
+			switch polls.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusNotModified)
+			case 2:
+				w.WriteHeader(http.StatusOK)
+			default:
+				<-r.Context().Done()
+			}
+		}
+	}))
+	defer srv.Close()
+
+	var n atomic.Int32
+	notify := func() { n.Add(1) }
+	cfg := config.FilesConf{Push: srv.URL + "/sse", Options: map[string]string{"auth": "header", "auth_header": "X-Token"}, SecretValue: "abc"}
+	err := subscribeHTTP(context.Background(), cfg, notify)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), n.Load())
+
+	n.Store(0)
+	old := PushRetryMinInterval
+	PushRetryMinInterval = 0
+	defer func() { PushRetryMinInterval = old }()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	cfg.Push = srv.URL + "/poll"
+	_ = subscribeHTTP(ctx, cfg, notify)
+	assert.Equal(t, int32(1), n.Load())
+	assert.Equal(t, int32(3), polls.Load())
+
+	cfg.SecretValue = "bad"
+	assert.NotNil(t, subscribeHTTP(context.Background(), cfg, notify))
+}
+
+func TestGetPushSubscriber(t *testing.T) {
+	_, err := getPushSubscriber("redis://app:config:changed")
+	assert.Nil(t, err)
+	_, err = getPushSubscriber("ws://127.0.0.1/watch")
+	assert.NotNil(t, err)
+	RegisterPushSubscriber("WS", func(ctx context.Context, cfg config.FilesConf, notify func()) error { return nil })
+	_, err = getPushSubscriber("ws://127.0.0.1/watch")
+	assert.Nil(t, err)
+	_, err = getPushSubscriber("127.0.0.1")
+	assert.NotNil(t, err)
+}
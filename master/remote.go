@@ -2,8 +2,10 @@ package master
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/fufuok/cache/xsync"
 	"github.com/fufuok/utils"
 
 	"github.com/fufuok/pkg/common"
@@ -12,6 +14,27 @@ import (
 	"github.com/fufuok/pkg/logger/sampler"
 )
 
+// 运行中的远端配置获取任务的立即获取信号
+var remoteTriggers = xsync.NewMap[chan struct{}, struct{}]()
+
+// FetchRemoteNow 所有运行中的远端配置获取任务立即获取一次配置, 返回任务数
+func FetchRemoteNow() (n int) {
+	remoteTriggers.Range(func(trigger chan struct{}, _ struct{}) bool {
+		notifyTrigger(trigger)
+		n++
+		return true
+	})
+	return
+}
+
+// 未处理的信号合并为一次
+func notifyTrigger(trigger chan<- struct{}) {
+	select {
+	case trigger <- struct{}{}:
+	default:
+	}
+}
+
 // 初始化获取远端配置
 func startRemotePipelines(ctx context.Context) {
 	// 定时获取远程主配置, 黑白名单配置
@@ -52,15 +75,26 @@ func getBlacklistRemoteConf(ctx context.Context) {
 }
 
 // GetRemoteConf 定时获取远端配置, 配合 RemotePipelines 使用
+// 配置了推送订阅 (cfg.Push) 时, 收到变更通知立即获取, 定时获取作为兜底
 // 注: 当主配置变化时, 该函数会退出并重新运行
 func GetRemoteConf(ctx context.Context, cfg config.FilesConf) {
 	id := common.GTimeNowString("060102150405.999999999")
 	logger.Warn().Str("id", id).Str("path", cfg.Path).Str("method", cfg.Method).
 		Msg("Remote config fetcher started")
+	trigger := make(chan struct{}, 1)
+	remoteTriggers.Store(trigger, struct{}{})
+	if cfg.Push != "" {
+		startPushSubscriber(ctx, id, cfg, trigger)
+	}
 	fetcher := func() {
+		defer remoteTriggers.Delete(trigger)
+		pushed := false
 		for {
-			wait := utils.FastIntn(cfg.RandomWait)
-			time.Sleep(time.Duration(wait) * time.Second)
+			// 推送触发时不做随机等待
+			if !pushed {
+				wait := utils.FastIntn(cfg.RandomWait)
+				time.Sleep(time.Duration(wait) * time.Second)
+			}
 			select {
 			case <-ctx.Done():
 				logger.Warn().Str("id", id).Str("path", cfg.Path).Str("method", cfg.Method).
@@ -70,15 +104,27 @@ func GetRemoteConf(ctx context.Context, cfg config.FilesConf) {
 			}
 			// 是否跳过更新远端配置
 			if !config.IsSkipRemoteConfig() {
-				if err := common.InvokeConfigMethod(cfg); err != nil {
+				if err := common.InvokeConfigMethod(cfg); errors.Is(err, config.ErrDataSourceVerify) {
+					// 内容校验失败, 本地文件未更新
+					logger.Error().Err(err).Str("id", id).Str("path", cfg.Path).Str("method", cfg.Method).
+						Msg("Remote config verification failed")
+					common.SendAlarm("", "Remote config verification failed: "+cfg.Path, err.Error())
+				} else if err != nil {
 					sampler.Error().Err(err).Str("id", id).Str("path", cfg.Path).Str("method", cfg.Method).
 						Msg("Failed to get remote config")
 				} else {
 					logger.Info().Str("id", id).Str("path", cfg.Path).Str("method", cfg.Method).
-						Msg("Execute remote config fetcher")
+						Bool("pushed", pushed).Msg("Execute remote config fetcher")
 				}
 			}
-			time.Sleep(cfg.GetConfDuration)
+			select {
+			case <-time.After(cfg.GetConfDuration):
+				pushed = false
+			case <-trigger:
+				// 推送通知或手动触发
+				pushed = true
+			case <-ctx.Done():
+			}
 		}
 	}
 	utils.SafeGo(fetcher, common.RecoverAlarm)
@@ -0,0 +1,38 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/logger"
+	"github.com/fufuok/pkg/logger/alarm"
+)
+
+// ReloadProbe 可由 App 指定配置热加载后的健康检查, 在所有 Pipeline.Runtime() 成功后执行
+// 返回错误时回滚到热加载前的配置; 平滑重启的新进程也以此检查就绪, 失败时旧进程继续运行
+var ReloadProbe func() error
+
+// 热加载后的健康检查
+func probeReload() (err error) {
+	if ReloadProbe == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("reload probe panic: %v", r)
+		}
+	}()
+	return ReloadProbe()
+}
+
+// 新配置应用失败时回滚: 恢复快照中的配置并以旧配置重新执行所有 Pipeline.Runtime()
+// 配置文件 MD5 已记录为新值, 文件再次变化前不会重复加载这份错误配置
+func rollbackConfig(snapshot *config.Snapshot, cause error) {
+	changes := config.LastDiff().Strings()
+	snapshot.Restore()
+	logger.Warn().Msg(">>>>>>> Rollback config <<<<<<<")
+	err := errors.Join(runtimeConfigPipeline(), runtimePipeline())
+	alarm.Error().Err(cause).Strs("changes", changes).AnErr("rollback_err", err).
+		Msg("Failed to apply new config, rolled back")
+}
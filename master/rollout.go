@@ -0,0 +1,255 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/json"
+	"github.com/fufuok/pkg/logger"
+)
+
+// 灰度发布状态
+const (
+	// RolloutInstalled 新版本已安装, 等待重启后进入观察期
+	RolloutInstalled = "installed"
+	// RolloutSoaking 观察期健康检查中
+	RolloutSoaking = "soaking"
+	// RolloutPassed 观察期通过
+	RolloutPassed = "passed"
+	// RolloutRolledBack 健康检查失败, 已回滚到上一版本
+	RolloutRolledBack = "rolled_back"
+)
+
+var (
+	// RolloutStateFile 灰度发布状态文件, 默认: etc/.{BinName}.rollout.json
+	RolloutStateFile string
+
+	// RolloutProbeInterval 观察期健康检查间隔
+	RolloutProbeInterval = 30 * time.Second
+
+	// RolloutMaxPanics 观察期允许的最大崩溃次数 (common.PanicCount), 超过时回滚
+	RolloutMaxPanics uint64
+
+	// RolloutMaxRestarts 观察期允许的最大重启次数, 超过时回滚
+	RolloutMaxRestarts = 1
+
+	rolloutProbesMu sync.RWMutex
+	rolloutProbes   = make(map[string]func() error)
+	rolloutMu       sync.Mutex
+)
+
+// RolloutState 灰度发布状态, 记录在 RolloutStateFile, 跨重启保持
+type RolloutState struct {
+	Strategy string    `json:"strategy"`
+	Previous string    `json:"previous"`
+	Target   string    `json:"target"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason"`
+	Restarts int       `json:"restarts"`
+	Time     time.Time `json:"time"`
+	// Planned 观察期内的主动重启 (restart_main, 平滑重启, 程序变化), 不计入重启次数
+	Planned bool `json:"planned"`
+	// Blocked 已回滚的版本, 不再自动安装, 需修改配置的 deb_version 为其他版本
+	Blocked []string `json:"blocked"`
+}
+
+// RegisterRolloutProbe 注册灰度发布观察期健康检查, 返回错误时回滚到上一版本
+// 如: master.RegisterRolloutProbe("http", middleware.ErrorRateProbe(0.05, 100))
+func RegisterRolloutProbe(name string, probe func() error) {
+	rolloutProbesMu.Lock()
+	rolloutProbes[name] = probe
+	rolloutProbesMu.Unlock()
+}
+
+// GetRolloutState 当前灰度发布状态, 无记录时返回 nil
+func GetRolloutState() (*RolloutState, error) {
+	body, err := os.ReadFile(rolloutStateFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	st := new(RolloutState)
+	if err := json.Unmarshal(body, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// 安装新版本并记录灰度发布状态, 重启后由 startRollout 进入观察期
+func installUpdate(cfg config.SYSConf) {
+	rolloutMu.Lock()
+	defer rolloutMu.Unlock()
+	st, err := GetRolloutState()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to read rollout state")
+	}
+	if st == nil {
+		st = new(RolloutState)
+	}
+	if slices.Contains(st.Blocked, cfg.DebVersion) {
+		logger.Warn().Str("ver", cfg.DebVersion).Strs("blocked", st.Blocked).
+			Msg("Installation skipped, version was rolled back")
+		return
+	}
+
+	previous := config.DebVersion
+	if !installVersion(cfg) {
+		return
+	}
+	st.Strategy = cfg.UpdateStrategy
+	st.Previous = previous
+	st.Target = cfg.DebVersion
+	st.Status = RolloutInstalled
+	st.Reason = ""
+	st.Restarts = 0
+	st.Time = time.Now()
+	saveRolloutState(st)
+}
+
+// 程序启动后检查灰度发布状态, 新版本在观察期内运行健康检查, 失败时回滚
+func startRollout() {
+	st, err := GetRolloutState()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to read rollout state")
+		return
+	}
+	if st == nil || config.DebVersion != st.Target {
+		return
+	}
+	switch st.Status {
+	case RolloutInstalled:
+	case RolloutSoaking:
+		// 观察期内重启 (崩溃或被杀), 主动重启不计入
+		if st.Planned {
+			st.Planned = false
+			break
+		}
+		st.Restarts++
+		if st.Restarts > RolloutMaxRestarts {
+			rollback(st, fmt.Errorf("restarted %d times during soak", st.Restarts))
+			return
+		}
+	default:
+		return
+	}
+
+	soak := config.Config().SYSConf.RolloutSoakDuration
+	if soak <= 0 {
+		st.Status = RolloutPassed
+		saveRolloutState(st)
+		return
+	}
+	st.Status = RolloutSoaking
+	saveRolloutState(st)
+	logger.Warn().Str("ver", st.Target).Str("previous", st.Previous).Dur("soak", soak).
+		Msg("Rollout soak started")
+
+	basePanics := common.PanicCount()
+	deadline := time.Now().Add(soak)
+	ticker := time.NewTicker(min(RolloutProbeInterval, soak))
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := runRolloutProbes(basePanics); err != nil {
+			rollback(st, err)
+			return
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	st.Status = RolloutPassed
+	saveRolloutState(st)
+	logger.Warn().Str("ver", st.Target).Dur("soak", soak).Msg("Rollout soak passed")
+}
+
+// 主动重启前记录到灰度发布状态, 避免观察期内被视为崩溃
+func markPlannedRestart() {
+	rolloutMu.Lock()
+	defer rolloutMu.Unlock()
+	st, err := GetRolloutState()
+	if err != nil || st == nil {
+		return
+	}
+	if st.Status != RolloutSoaking || st.Target != config.DebVersion || st.Planned {
+		return
+	}
+	st.Planned = true
+	saveRolloutState(st)
+}
+
+// 运行所有健康检查, 返回所有失败原因
+func runRolloutProbes(basePanics uint64) error {
+	var errs []error
+	if n := common.PanicCount() - basePanics; n > RolloutMaxPanics {
+		errs = append(errs, fmt.Errorf("panics: %d > %d", n, RolloutMaxPanics))
+	}
+	rolloutProbesMu.RLock()
+	defer rolloutProbesMu.RUnlock()
+	for name, probe := range rolloutProbes {
+		if err := safeProbe(probe); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func safeProbe(probe func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("probe panic: %v", r)
+		}
+	}()
+	return probe()
+}
+
+// 回滚到上一版本, 并将当前版本加入禁止安装列表
+func rollback(st *RolloutState, cause error) {
+	rolloutMu.Lock()
+	defer rolloutMu.Unlock()
+	st.Status = RolloutRolledBack
+	st.Reason = cause.Error()
+	st.Time = time.Now()
+	if !slices.Contains(st.Blocked, st.Target) {
+		st.Blocked = append(st.Blocked, st.Target)
+	}
+	saveRolloutState(st)
+
+	info := fmt.Sprintf("Rollout of %s failed, rolling back to %s", st.Target, st.Previous)
+	logger.Error().Err(cause).Str("ver", st.Target).Str("previous", st.Previous).Msg(info)
+	if st.Previous == "" {
+		common.SendAlarm("", info, "previous version unknown, manual intervention required: "+st.Reason)
+		return
+	}
+	common.SendAlarm("", info, st.Reason)
+
+	cfg := config.Config().SYSConf
+	cfg.UpdateStrategy = st.Strategy
+	cfg.DebVersion = st.Previous
+	// 配置的 SHA-256 属于目标版本, 回滚版本使用 {url}.sha256 校验
+	cfg.BinarySHA256 = ""
+	if !installVersion(cfg) {
+		common.SendAlarm("", "Rollback to "+st.Previous+" failed", st.Reason)
+	}
+}
+
+func saveRolloutState(st *RolloutState) {
+	if err := os.WriteFile(rolloutStateFile(), json.MustJSONIndent(st), 0o600); err != nil {
+		logger.Warn().Err(err).Str("status", st.Status).Msg("Failed to save rollout state")
+	}
+}
+
+func rolloutStateFile() string {
+	if RolloutStateFile != "" {
+		return RolloutStateFile
+	}
+	return filepath.Join(config.ConfigPath, "."+config.BinName+".rollout.json")
+}
@@ -0,0 +1,90 @@
+package master
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+)
+
+func TestRolloutProbes(t *testing.T) {
+	base := common.PanicCount()
+	assert.Nil(t, runRolloutProbes(base))
+
+	RegisterRolloutProbe("ok", func() error { return nil })
+	RegisterRolloutProbe("bad", func() error { return errors.New("error rate") })
+	RegisterRolloutProbe("panic", func() error { panic("boom") })
+	defer func() {
+		rolloutProbesMu.Lock()
+		delete(rolloutProbes, "ok")
+		delete(rolloutProbes, "bad")
+		delete(rolloutProbes, "panic")
+		rolloutProbesMu.Unlock()
+	}()
+	err := runRolloutProbes(base)
+	assert.NotNil(t, err)
+	assert.Contains(t, "bad: error rate", err.Error())
+	assert.Contains(t, "panic: probe panic: boom", err.Error())
+}
+
+func TestRolloutState(t *testing.T) {
+	RolloutStateFile = filepath.Join(t.TempDir(), "rollout.json")
+	defer func() {
+		RolloutStateFile = ""
+	}()
+
+	st, err := GetRolloutState()
+	assert.Nil(t, err)
+	assert.Nil(t, st)
+
+	saveRolloutState(&RolloutState{
+		Previous: "1.0.0",
+		Target:   "1.0.1",
+		Status:   RolloutRolledBack,
+		Reason:   "panics: 1 > 0",
+		Blocked:  []string{"1.0.1"},
+	})
+	st, err = GetRolloutState()
+	assert.Nil(t, err)
+	assert.Equal(t, RolloutRolledBack, st.Status)
+	assert.Equal(t, "panics: 1 > 0", st.Reason)
+	assert.Equal(t, []string{"1.0.1"}, st.Blocked)
+
+	// 已回滚的版本不再安装
+	debInstalling.Store(true)
+	defer debInstalling.Store(false)
+	installUpdate(config.SYSConf{DebVersion: "1.0.1"})
+	st, _ = GetRolloutState()
+	assert.Equal(t, RolloutRolledBack, st.Status)
+
+	// 安装失败时不记录
+	installUpdate(config.SYSConf{DebVersion: "1.0.2"})
+	st, _ = GetRolloutState()
+	assert.Equal(t, "1.0.1", st.Target)
+}
+
+func TestMarkPlannedRestart(t *testing.T) {
+	RolloutStateFile = filepath.Join(t.TempDir(), "rollout.json")
+	ver := config.DebVersion
+	defer func() {
+		RolloutStateFile = ""
+		config.DebVersion = ver
+	}()
+	config.DebVersion = "1.0.1"
+
+	// 非观察期不记录
+	saveRolloutState(&RolloutState{Target: "1.0.1", Status: RolloutPassed})
+	markPlannedRestart()
+	st, _ := GetRolloutState()
+	assert.False(t, st.Planned)
+
+	saveRolloutState(&RolloutState{Target: "1.0.1", Status: RolloutSoaking})
+	markPlannedRestart()
+	st, _ = GetRolloutState()
+	assert.True(t, st.Planned)
+	assert.Equal(t, 0, st.Restarts)
+}
@@ -0,0 +1,138 @@
+package master
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fufuok/utils"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/logger"
+)
+
+// BinaryVersionSuffix 二进制升级后记录当前版本的文件后缀, 完整路径为: {程序路径}.version
+var BinaryVersionSuffix = ".version"
+
+// 按配置的升级方式安装新版本 (cfg.DebVersion), 返回是否安装成功
+func installVersion(cfg config.SYSConf) bool {
+	if cfg.UpdateStrategy == config.UpdateStrategyBinary {
+		return installBinary(cfg)
+	}
+	return installDeb(cfg.DebVersion)
+}
+
+// 下载指定版本的二进制文件, 校验后替换当前程序, 由 mainWatcher 检测到程序变化后重启
+func installBinary(cfg config.SYSConf) bool {
+	ver := cfg.DebVersion
+	if !debInstalling.CompareAndSwap(false, true) {
+		logger.Warn().Str("ver", ver).Msg("Binary installation skipped")
+		return false
+	}
+	defer debInstalling.Store(false)
+
+	// 随机一定的时间执行, 减少下载服务器压力
+	wait := utils.FastIntn(config.Config().MainConf.RandomWait)
+	time.Sleep(time.Duration(wait) * time.Second)
+
+	start := time.Now()
+	url := config.BinaryUpdateURL(cfg.BinaryURL, ver)
+	if err := updateBinary(mainFile, url, cfg); err != nil {
+		logger.Error().Err(err).Str("ver", ver).Str("url", url).Dur("took", time.Since(start)).
+			Msg("Binary installation failed")
+		common.SendAlarm("", "Binary installation failed: "+ver, err.Error())
+		return false
+	}
+	if err := os.WriteFile(mainFile+BinaryVersionSuffix, []byte(ver), 0o644); err != nil {
+		logger.Warn().Err(err).Str("ver", ver).Msg("Failed to write binary version file")
+	}
+	config.DebVersion = ver
+	logger.Warn().Str("ver", ver).Str("url", url).Str("main", mainFile).Dur("took", time.Since(start)).
+		Msg("Binary installed")
+	return true
+}
+
+// 下载到程序同目录的临时文件, 校验 SHA-256 和 Ed25519 签名后原子替换
+func updateBinary(exe, url string, cfg config.SYSConf) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), filepath.Base(exe)+".*.download")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	h := sha256.New()
+	resp, err := common.ReqDownload.R().SetOutput(io.MultiWriter(tmp, h)).Get(url)
+	_ = tmp.Close()
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccessState() {
+		return fmt.Errorf("download failed: [%d] %s", resp.StatusCode, resp.Status)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	// SHA-256: 优先使用配置值, 否则下载 {url}.sha256 (sha256sum 输出格式)
+	expected := cfg.BinarySHA256
+	if expected == "" {
+		if expected, err = getBinaryMeta(url + ".sha256"); err != nil {
+			return err
+		}
+		expected, _, _ = strings.Cut(expected, " ")
+	}
+	if !strings.EqualFold(sum, expected) {
+		return fmt.Errorf("sha256 mismatch: %s != %s", sum, expected)
+	}
+
+	// 签名: {url}.sig, 对二进制文件内容的 Ed25519 签名
+	sig, err := getBinaryMeta(url + ".sig")
+	if err != nil {
+		return err
+	}
+	body, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if err := config.CheckEd25519Signature(cfg.BinaryPublicKey, body, sig); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0o755)
+	if info, err := os.Stat(exe); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+func getBinaryMeta(url string) (string, error) {
+	resp, err := common.ReqDownload.R().Get(url)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsSuccessState() {
+		return "", fmt.Errorf("download failed: [%d] %s", resp.StatusCode, url)
+	}
+	return strings.TrimSpace(resp.String()), nil
+}
+
+// BinaryVersion 二进制升级方式下当前程序版本: 升级时记录的版本, 未升级过时为 config.Version
+func BinaryVersion() string {
+	if mainFile != "" {
+		if b, err := os.ReadFile(mainFile + BinaryVersionSuffix); err == nil {
+			if ver := strings.TrimSpace(string(b)); ver != "" {
+				return ver
+			}
+		}
+	}
+	return config.Version
+}
@@ -0,0 +1,76 @@
+package master
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+	"github.com/imroc/req/v3"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+)
+
+func TestUpdateBinary(t *testing.T) {
+	if common.ReqDownload == nil {
+		common.ReqDownload = req.C()
+	}
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	bin := []byte("#!/bin/sh\necho v2\n")
+	sum := sha256.Sum256(bin)
+	sig := ed25519.Sign(priv, bin)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/v2/app":
+			_, _ = w.Write(bin)
+		case "/app/v2/app.sha256":
+			_, _ = w.Write([]byte(hex.EncodeToString(sum[:]) + "  app\n"))
+		case "/app/v2/app.sig":
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(sig)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	exe := filepath.Join(t.TempDir(), "app")
+	assert.Nil(t, os.WriteFile(exe, []byte("v1"), 0o750))
+	cfg := config.SYSConf{BinaryPublicKey: hex.EncodeToString(pub)}
+
+	// 签名公钥不匹配, 不替换
+	other, _, _ := ed25519.GenerateKey(nil)
+	bad := cfg
+	bad.BinaryPublicKey = hex.EncodeToString(other)
+	assert.NotNil(t, updateBinary(exe, srv.URL+"/app/v2/app", bad))
+	// SHA-256 不匹配
+	bad = cfg
+	bad.BinarySHA256 = hex.EncodeToString(make([]byte, 32))
+	assert.NotNil(t, updateBinary(exe, srv.URL+"/app/v2/app", bad))
+	assert.NotNil(t, updateBinary(exe, srv.URL+"/app/v3/app", cfg))
+	b, _ := os.ReadFile(exe)
+	assert.Equal(t, "v1", string(b))
+
+	assert.Nil(t, updateBinary(exe, srv.URL+"/app/v2/app", cfg))
+	b, _ = os.ReadFile(exe)
+	assert.Equal(t, bin, b)
+	info, _ := os.Stat(exe)
+	assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+
+	// 临时文件已清理
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestBinaryUpdateURL(t *testing.T) {
+	u := config.BinaryUpdateURL("https://dl.example.com/{name}/{version}/{name}-{os}-{arch}", "1.2.3")
+	assert.Equal(t, "https://dl.example.com/"+config.BinName+"/1.2.3/"+config.BinName+"-"+runtime.GOOS+"-"+runtime.GOARCH, u)
+}
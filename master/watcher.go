@@ -1,12 +1,17 @@
 package master
 
 import (
+	"io/fs"
 	"log"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fufuok/cache/xsync"
 	"github.com/fufuok/utils"
+	"github.com/fufuok/utils/xfile"
 	"github.com/fufuok/utils/xhash"
 
 	"github.com/fufuok/pkg/common"
@@ -23,9 +28,9 @@ var (
 	ConfigLoadTime time.Time
 
 	// watchers 自助添加的文件变化监控器
-	watchers = xsync.NewMap[string, Watcher]()
+	watchers = xsync.NewMap[string, *watcherState]()
 
-	// 存放监控器标识和对应的 MD5
+	// 存放主程序和配置文件监控器标识和对应的 MD5
 	watcherMD5 = xsync.NewMap[string, string]()
 
 	isRuntime bool
@@ -37,6 +42,24 @@ var (
 
 	// 待监控内容变化的额外文件列表
 	extraWatcherFiles []string
+
+	// 手动触发配置重载请求, 未处理的请求合并为一次
+	reloadRequest = make(chan struct{}, 1)
+
+	// 停止主监控器, 文件事件监控和自助添加的监控器 (平滑重启移交后), 仅执行一次
+	watcherStop = make(chan struct{})
+	stopWatcher = sync.OnceFunc(func() {
+		close(watcherStop)
+		if notifier != nil {
+			_ = notifier.Close()
+		}
+		watchers.Range(func(key string, _ *watcherState) bool {
+			if st, ok := watchers.LoadAndDelete(key); ok {
+				st.close()
+			}
+			return true
+		})
+	})
 )
 
 // Watcher 文件变化监控器
@@ -47,18 +70,59 @@ type Watcher struct {
 	// 待监控的文件列表
 	Files []string
 
+	// 待监控的 glob 模式 (filepath.Match 语法, 如: /etc/app/rules/*.json) 或目录 (监控目录树下的所有文件)
+	Paths []string
+
 	// 文件列表中文件内容变化时执行
 	Func func()
 
+	// 文件新增, 修改或删除时执行, 参数为变化的文件列表, 可与 Func 同时使用
+	OnChange func(ev WatcherEvent)
+
 	// 始终执行, 不关注文件内容是否变化
 	Always bool
 
-	// 基于文件内容是否变化的标记生成函数, 默认为: MD5Files
+	// 独立的定时检查间隔, 为 0 时随主监控器定时检查
+	Interval time.Duration
+
+	// 文件事件合并时间, 在 WatcherDebounce 合并之后再等待, 为 0 时不额外等待
+	Debounce time.Duration
+
+	// 基于文件内容是否变化的标记生成函数, 默认为: MD5Files, 每个文件单独调用
 	HashGenerator func(...string) string
 }
 
+// WatcherEvent 监控器文件变化列表, Always 监控器无变化时均为空
+type WatcherEvent struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// Empty 无文件变化
+func (e WatcherEvent) Empty() bool {
+	return len(e.Added) == 0 && len(e.Modified) == 0 && len(e.Removed) == 0
+}
+
+// 监控器运行状态
+type watcherState struct {
+	w Watcher
+
+	// 影响监控范围的目录: 文件所在目录 (精确匹配), 目录树根 (前缀匹配)
+	dirs  map[string]struct{}
+	trees []string
+
+	mu     sync.Mutex
+	hashes map[string]string
+	timer  *time.Timer
+	stop   chan struct{}
+
+	// 串行执行检查, 避免定时检查与事件检查并发时比较结果交错
+	checkMu sync.Mutex
+}
+
 func (w Watcher) Start() {
-	if w.Key == "" || w.Func == nil {
+	if w.Key == "" || (w.Func == nil && w.OnChange == nil) {
 		return
 	}
 
@@ -66,11 +130,15 @@ func (w Watcher) Start() {
 		w.HashGenerator = MD5Files
 	}
 
-	md5 := w.HashGenerator(w.Files...)
-	watcherMD5.Store(w.Key, md5)
-	watchers.Store(w.Key, w)
+	st := newWatcherState(w)
+	if old, ok := watchers.LoadAndStore(w.Key, st); ok {
+		old.close()
+	}
+	if w.Interval > 0 {
+		go st.run()
+	}
 	if isRuntime {
-		logger.Warn().Str("key", w.Key).Strs("files", w.Files).Msg("Watcher started")
+		logger.Warn().Str("key", w.Key).Strs("files", w.Files).Strs("paths", w.Paths).Msg("Watcher started")
 	}
 }
 
@@ -78,11 +146,197 @@ func (w Watcher) Stop() {
 	if w.Key == "" {
 		return
 	}
-	watchers.Delete(w.Key)
-	watcherMD5.Delete(w.Key)
+	if st, ok := watchers.LoadAndDelete(w.Key); ok {
+		st.close()
+	}
 	if isRuntime {
-		logger.Warn().Str("key", w.Key).Strs("files", w.Files).Msg("Watcher stopped")
+		logger.Warn().Str("key", w.Key).Strs("files", w.Files).Strs("paths", w.Paths).Msg("Watcher stopped")
+	}
+}
+
+func newWatcherState(w Watcher) *watcherState {
+	st := &watcherState{
+		w:    w,
+		dirs: make(map[string]struct{}),
+		stop: make(chan struct{}),
+	}
+	for _, f := range w.Files {
+		st.dirs[cleanDir(f)] = struct{}{}
 	}
+	for _, p := range w.Paths {
+		p = absPath(p)
+		if xfile.IsDir(p) {
+			st.trees = append(st.trees, p)
+			continue
+		}
+		dir := filepath.Dir(p)
+		if i := strings.IndexAny(dir, `*?[\`); i >= 0 {
+			// 目录含通配符, 按无通配符的上级目录树匹配
+			st.trees = append(st.trees, filepath.Dir(dir[:i+1]))
+			continue
+		}
+		st.dirs[dir] = struct{}{}
+	}
+	files, dirs := st.expand()
+	st.hashes = st.hash(files)
+	watchDirs(dirs...)
+	return st
+}
+
+// 监控范围内的文件及需监控事件的目录
+func (st *watcherState) expand() (files, dirs []string) {
+	seen := make(map[string]struct{})
+	add := func(f string) {
+		if _, ok := seen[f]; !ok {
+			seen[f] = struct{}{}
+			files = append(files, f)
+		}
+	}
+	for _, f := range st.w.Files {
+		if xfile.IsFile(f) {
+			add(absPath(f))
+		}
+	}
+	for dir := range st.dirs {
+		dirs = append(dirs, dir)
+	}
+	for _, p := range st.w.Paths {
+		p = absPath(p)
+		if xfile.IsDir(p) {
+			_ = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if d.IsDir() {
+					dirs = append(dirs, path)
+				} else if d.Type().IsRegular() {
+					add(path)
+				}
+				return nil
+			})
+			continue
+		}
+		matches, _ := filepath.Glob(p)
+		for _, m := range matches {
+			if xfile.IsFile(m) {
+				add(m)
+				dirs = append(dirs, filepath.Dir(m))
+			}
+		}
+	}
+	slices.Sort(files)
+	return files, dirs
+}
+
+func (st *watcherState) hash(files []string) map[string]string {
+	hashes := make(map[string]string, len(files))
+	for _, f := range files {
+		hashes[f] = st.w.HashGenerator(f)
+	}
+	return hashes
+}
+
+// 有文件事件的目录是否影响监控范围
+func (st *watcherState) affected(dirs map[string]struct{}) bool {
+	for dir := range dirs {
+		if _, ok := st.dirs[dir]; ok {
+			return true
+		}
+		for _, root := range st.trees {
+			if dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 检查文件变化, 有变化或 Always 时执行回调
+func (st *watcherState) check() {
+	st.checkMu.Lock()
+	defer st.checkMu.Unlock()
+	// 已停止 (如停止后触发的合并定时器)
+	select {
+	case <-st.stop:
+		return
+	default:
+	}
+
+	files, dirs := st.expand()
+	hashes := st.hash(files)
+	st.mu.Lock()
+	var ev WatcherEvent
+	for f, h := range hashes {
+		old, ok := st.hashes[f]
+		switch {
+		case !ok:
+			ev.Added = append(ev.Added, f)
+		case old != h:
+			ev.Modified = append(ev.Modified, f)
+		}
+	}
+	for f := range st.hashes {
+		if _, ok := hashes[f]; !ok {
+			ev.Removed = append(ev.Removed, f)
+		}
+	}
+	st.hashes = hashes
+	st.mu.Unlock()
+
+	// 新增的子目录
+	watchDirs(dirs...)
+	if !st.w.Always && ev.Empty() {
+		return
+	}
+	slices.Sort(ev.Added)
+	slices.Sort(ev.Modified)
+	slices.Sort(ev.Removed)
+	utils.SafeGo(func() {
+		if st.w.Func != nil {
+			st.w.Func()
+		}
+		if st.w.OnChange != nil {
+			st.w.OnChange(ev)
+		}
+	})
+}
+
+// 文件事件触发检查, 按监控器的合并时间延迟
+func (st *watcherState) schedule() {
+	if st.w.Debounce <= 0 {
+		st.check()
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.timer == nil {
+		st.timer = time.AfterFunc(st.w.Debounce, st.check)
+		return
+	}
+	st.timer.Reset(st.w.Debounce)
+}
+
+// 独立间隔定时检查
+func (st *watcherState) run() {
+	ticker := time.NewTicker(st.w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-st.stop:
+			return
+		case <-ticker.C:
+			st.check()
+		}
+	}
+}
+
+func (st *watcherState) close() {
+	close(st.stop)
+	st.mu.Lock()
+	if st.timer != nil {
+		st.timer.Stop()
+	}
+	st.mu.Unlock()
 }
 
 func initWatcher() {
@@ -102,9 +356,10 @@ func initWatcher() {
 	logger.Warn().Str("main", mainFile).Str(config.DebName, config.DebVersion).Msg("Watching")
 	logger.Warn().Strs("configs", confFiles).Msg("Watching")
 	logger.Warn().RawJSON("data", json.MustJSON(config.Config().NodeConf.NodeInfo)).Msg("Node info updated")
+	recordConfigHistory(confFiles)
 
 	var keys []string
-	watchers.Range(func(key string, _ Watcher) bool {
+	watchers.Range(func(key string, _ *watcherState) bool {
 		keys = append(keys, key)
 		return true
 	})
@@ -116,6 +371,7 @@ func initWatcher() {
 }
 
 // 监听程序二进制变化(重启)和配置文件(热加载)
+// 文件事件监控可用时, 文件变化后即时检查, 定时器仅运行 Always 监控器和定期全量检查
 func startWatcher() {
 	cfg := config.Config().SYSConf
 	interval := cfg.WatcherIntervalDuration
@@ -125,30 +381,80 @@ func startWatcher() {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if c := mainWatcher(); c {
+	dirs := append(appWatcherDirs(), cleanDir(mainFile))
+	for _, f := range configFiles() {
+		dirs = append(dirs, cleanDir(f))
+	}
+	events := startFileNotifier(dirs...)
+	if events != nil {
+		logger.Warn().Dur("debounce", WatcherDebounce).Dur("rescan", WatcherRescanInterval).Msg("File notify enabled")
+	}
+	lastScan := time.Now()
+
+	for {
+		var changed changedFiles
+		tick := false
+		select {
+		case <-watcherStop:
+			return
+		case <-ticker.C:
+			tick = true
+			if events == nil || time.Since(lastScan) >= WatcherRescanInterval || rewatchDirs() {
+				changed.all = true
+				lastScan = time.Now()
+			}
+		case dirs := <-events:
+			// 事件队列溢出时全量检查
+			if _, ok := dirs[notifyOverflow]; ok {
+				changed.all = true
+				lastScan = time.Now()
+				break
+			}
+			changed.dirs = dirs
+		case <-reloadRequest:
+			// 强制重载配置, 不论配置文件内容是否变化
+			changed.all = true
+			watcherMD5.Store(MainWatcherConfKey, "")
+			logger.Warn().Msg("Reload requested")
+		}
+
+		if c := mainWatcher(changed); c {
 			continue
 		}
 
-		appWatcher()
+		appWatcher(changed, tick)
 
-		if c := configWatcher(); c {
+		// 热加载前保存配置快照, 新配置应用失败时回滚
+		snapshot := config.TakeSnapshot()
+		if c := configWatcher(changed); c {
 			continue
 		}
+		// 配置文件列表可能变化 (如新增环境文件)
+		watchFiles(configFiles()...)
 
 		// 第一时间加载新配置
-		runtimeConfigPipeline()
+		if err := runtimeConfigPipeline(); err != nil {
+			rollbackConfig(snapshot, err)
+			continue
+		}
+		if err := runtimePipeline(); err != nil {
+			rollbackConfig(snapshot, err)
+			continue
+		}
+		if err := probeReload(); err != nil {
+			rollbackConfig(snapshot, err)
+			continue
+		}
+		ConfigLoadTime = common.GTimeNow()
+		logSecondarySecretKeys()
 		cfg = config.Config().SYSConf
 
-		// 同步更新机器上现在的包版本
+		// 新配置确认生效后, 同步更新机器上现在的包版本, 再检查升级或重启
 		config.DebVersion = getCurrentDebVersion()
 		if c := checkUpgradeOrRestart(cfg); c {
 			continue
 		}
 
-		runtimePipeline()
-		ConfigLoadTime = common.GTimeNow()
-
 		// 更新配置文件监控周期
 		if interval != cfg.WatcherIntervalDuration {
 			interval = cfg.WatcherIntervalDuration
@@ -167,7 +473,10 @@ func startWatcher() {
 	}
 }
 
-func mainWatcher() (needContinue bool) {
+func mainWatcher(changed changedFiles) (needContinue bool) {
+	if !changed.has(mainFile) {
+		return
+	}
 	// 程序二进制变化时重启
 	md5New := MD5Files(mainFile)
 	md5Main, _ := watcherMD5.LoadAndStore(MainWatcherKey, md5New)
@@ -175,39 +484,75 @@ func mainWatcher() (needContinue bool) {
 		return
 	}
 	logger.Warn().Str("deb_version", config.DebVersion).Msg(">>>>>>> Restart main <<<<<<<")
+	markPlannedRestart()
 	restartChan <- true
 	return true
 }
 
 // 运行应用自助添加的监控器
-func appWatcher() {
-	watchers.Range(func(k string, w Watcher) bool {
-		md5New := w.HashGenerator(w.Files...)
-		md5Old, _ := watcherMD5.LoadAndStore(k, md5New)
-		if w.Always || md5New != md5Old {
-			utils.SafeGo(w.Func)
+// 定时器触发时检查 Always 监控器, 以及全量检查时的所有监控器 (独立间隔的除外), 文件事件触发时检查受影响的监控器
+func appWatcher(changed changedFiles, tick bool) {
+	watchers.Range(func(_ string, st *watcherState) bool {
+		if tick {
+			if st.w.Interval == 0 && (st.w.Always || changed.all) {
+				st.check()
+			}
+			return true
+		}
+		if st.affected(changed.dirs) {
+			st.schedule()
 		}
 		return true
 	})
 }
 
-func configWatcher() (needContinue bool) {
+func configWatcher(changed changedFiles) (needContinue bool) {
+	if !changed.has(configFiles()...) {
+		return true
+	}
 	// 系统配置检查和重载
-	md5New, _ := MD5ConfigFiles()
+	md5New, confFiles := MD5ConfigFiles()
 	md5Conf, _ := watcherMD5.LoadAndStore(MainWatcherConfKey, md5New)
 	if md5New == md5Conf {
 		return true
 	}
 	ConfigModTime = common.GTimeNow()
+	recordConfigHistory(confFiles)
 
-	// 任意配置文件变化, 热加载所有配置
+	// 任意配置文件变化, 热加载所有配置, 失败时保持原配置
 	if err := config.LoadConfig(); err != nil {
 		logger.Error().Err(err).Msg("Failed to reload config")
 		return true
 	}
+	logConfigDiff(config.LastDiff())
 	return false
 }
 
+// 记录配置变化明细 (敏感配置项已脱敏)
+func logConfigDiff(diff *config.ConfigDiff) {
+	if diff.Empty() {
+		logger.Warn().Msg("Config reloaded without changes")
+		return
+	}
+	logger.Warn().Strs("changes", diff.Strings()).Msg("Config changed")
+}
+
+// 记录配置文件历史版本, 内容与最近版本相同的文件忽略
+func recordConfigHistory(files []string) {
+	for _, f := range files {
+		if _, err := config.History.Record(f, config.HistorySourceLocal); err != nil {
+			logger.Warn().Err(err).Str("file", f).Msg("Failed to record config history")
+		}
+	}
+}
+
+// 密钥轮换期间, 提示仍使用次基础密钥 (旧密钥) 加密的值
+func logSecondarySecretKeys() {
+	if keys := config.SecondarySecretKeys(); len(keys) > 0 {
+		logger.Warn().Strs("keys", keys).Msg("Values still encrypted with the secondary base secret, re-encrypt them before removing it")
+	}
+}
+
 func checkUpgradeOrRestart(cfg config.SYSConf) (needContinue bool) {
 	// 安装新版本, 每当配置有变化时才检测
 	if cfg.DebVersion != "" && config.DebVersion != "" && config.DebVersion != cfg.DebVersion {
@@ -216,21 +561,34 @@ func checkUpgradeOrRestart(cfg config.SYSConf) (needContinue bool) {
 		logger.Warn().
 			Strs("deb_versions", []string{config.DebVersion, cfg.DebVersion}).
 			Bool("to_install", toInstall).Str("ip", common.ExternalIPv4).Uint64("threshold", threshold).
+			Str("strategy", cfg.UpdateStrategy).
 			Msg("Starting canary deployment")
 		if toInstall {
-			go installDeb(cfg.DebVersion)
+			go installUpdate(cfg)
 		}
 	}
 
 	// 重启程序指令
 	if cfg.RestartMain {
 		logger.Warn().Str("deb_version", config.DebVersion).Msg(">>>>>>> Restart main(config) <<<<<<<")
+		markPlannedRestart()
 		restartChan <- true
 		return true
 	}
 	return
 }
 
+// Reload 立即重新加载配置文件 (不论内容是否变化), 并运行 Runtime 阶段的 Pipeline
+// 返回 false 表示已有未处理的重载请求
+func Reload() bool {
+	select {
+	case reloadRequest <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
 // SetExtraWatcherFiles 设置额外的文件到内容变化监控列表
 func SetExtraWatcherFiles(confFile ...string) {
 	extraWatcherFiles = confFile
@@ -238,13 +596,36 @@ func SetExtraWatcherFiles(confFile ...string) {
 
 // MD5ConfigFiles 配置文件 MD5, 有变化时重载系统配置项
 func MD5ConfigFiles() (md5 string, confFiles []string) {
-	confFiles = append(confFiles, config.ConfigFile, config.WhitelistConfigFile, config.BlacklistConfigFile)
+	confFiles = configFiles()
+	md5 = MD5Files(confFiles...)
+	return
+}
+
+// 需监控内容变化的配置文件列表
+func configFiles() (confFiles []string) {
+	confFiles = append(confFiles, config.GetConfigFiles()...)
+	// 尚不存在的分层配置文件, 创建后触发热加载
+	for _, f := range config.GetConfigLayerFiles() {
+		if !slices.Contains(confFiles, f) {
+			confFiles = append(confFiles, f)
+		}
+	}
+	confFiles = append(confFiles, config.WhitelistConfigFile, config.BlacklistConfigFile)
 	confFiles = append(confFiles, config.GetEnvFiles()...)
 	confFiles = append(confFiles, extraWatcherFiles...)
 	if config.NodeInfoFile != "" {
 		confFiles = append(confFiles, config.NodeInfoFile)
 	}
-	md5 = MD5Files(confFiles...)
+	return
+}
+
+// 应用自助添加的监控器需监控事件的目录
+func appWatcherDirs() (dirs []string) {
+	watchers.Range(func(_ string, st *watcherState) bool {
+		_, ds := st.expand()
+		dirs = append(dirs, ds...)
+		return true
+	})
 	return
 }
 
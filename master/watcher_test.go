@@ -0,0 +1,120 @@
+package master
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fufuok/utils/assert"
+)
+
+func TestWatcherPaths(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	assert.Nil(t, os.MkdirAll(sub, 0o755))
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(sub, "b.txt")
+	assert.Nil(t, os.WriteFile(a, []byte("a"), 0o644))
+	assert.Nil(t, os.WriteFile(b, []byte("b"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "x.txt"), []byte("x"), 0o644))
+
+	evs := make(chan WatcherEvent, 1)
+	w := Watcher{
+		Key:      "test-paths",
+		Paths:    []string{filepath.Join(dir, "*.json"), sub},
+		OnChange: func(ev WatcherEvent) { evs <- ev },
+	}
+	w.Start()
+	defer w.Stop()
+	st, ok := watchers.Load(w.Key)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(st.hashes))
+
+	assert.True(t, st.affected(map[string]struct{}{dir: {}}))
+	assert.True(t, st.affected(map[string]struct{}{filepath.Join(sub, "deep"): {}}))
+	assert.False(t, st.affected(map[string]struct{}{filepath.Dir(dir): {}}))
+
+	// 无变化时不回调
+	st.check()
+	select {
+	case <-evs:
+		t.Fatal("unexpected event")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c := filepath.Join(sub, "deep", "c.txt")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(c), 0o755))
+	assert.Nil(t, os.WriteFile(c, []byte("c"), 0o644))
+	assert.Nil(t, os.WriteFile(a, []byte("aa"), 0o644))
+	assert.Nil(t, os.Remove(b))
+	st.check()
+	select {
+	case ev := <-evs:
+		assert.Equal(t, []string{c}, ev.Added)
+		assert.Equal(t, []string{a}, ev.Modified)
+		assert.Equal(t, []string{b}, ev.Removed)
+	case <-time.After(time.Second):
+		t.Fatal("change event not received")
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.conf")
+	assert.Nil(t, os.WriteFile(a, []byte("a"), 0o644))
+
+	calls := make(chan struct{}, 10)
+	w := Watcher{
+		Key:      "test-debounce",
+		Files:    []string{a},
+		Func:     func() { calls <- struct{}{} },
+		Debounce: 100 * time.Millisecond,
+	}
+	w.Start()
+	defer w.Stop()
+	st, _ := watchers.Load(w.Key)
+
+	changed := changedFiles{dirs: map[string]struct{}{dir: {}}}
+	for i := range 3 {
+		assert.Nil(t, os.WriteFile(a, []byte{byte('b' + i)}, 0o644))
+		appWatcher(changed, false)
+		time.Sleep(20 * time.Millisecond)
+	}
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("debounced check not run")
+	}
+	select {
+	case <-calls:
+		t.Fatal("check run more than once")
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.Equal(t, 1, len(st.hashes))
+}
+
+func TestWatcherCheckAfterStop(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.conf")
+	assert.Nil(t, os.WriteFile(a, []byte("a"), 0o644))
+
+	calls := make(chan struct{}, 10)
+	w := Watcher{
+		Key:   "test-check-stop",
+		Files: []string{a},
+		Func:  func() { calls <- struct{}{} },
+	}
+	w.Start()
+	st, _ := watchers.Load(w.Key)
+	w.Stop()
+
+	// 已停止的监控器不再检查
+	assert.Nil(t, os.WriteFile(a, []byte("b"), 0o644))
+	st.check()
+	select {
+	case <-calls:
+		t.Fatal("stopped watcher checked")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
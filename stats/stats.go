@@ -44,10 +44,11 @@ func SYSStats() map[string]any {
 		},
 		// 配置信息
 		"Config": map[string]any{
-			"LogLevel":       zerolog.Level(config.Config().LogConf.Level).String(),
-			"ConfigModTime":  master.ConfigModTime,
-			"ConfigLoadTime": master.ConfigLoadTime,
-			"Debug":          config.Debug,
+			"LogLevel":             zerolog.Level(config.Config().LogConf.Level).String(),
+			"ConfigModTime":        master.ConfigModTime,
+			"ConfigLoadTime":       master.ConfigLoadTime,
+			"Debug":                config.Debug,
+			"RemoteVerifyFailures": config.VerifyFailures(),
 		},
 		// 时间信息
 		"Time": map[string]any{
@@ -12,10 +12,11 @@ func SYSStatsDesc() map[string]any {
 			"JSON":       "使用的JSON库",
 		},
 		"Config": map[string]string{
-			"LogLevel":       "日志级别",
-			"ConfigModTime":  "配置文件修改时间",
-			"ConfigLoadTime": "配置加载时间",
-			"Debug":          "是否开启调试模式",
+			"LogLevel":             "日志级别",
+			"ConfigModTime":        "配置文件修改时间",
+			"ConfigLoadTime":       "配置加载时间",
+			"Debug":                "是否开启调试模式",
+			"RemoteVerifyFailures": "远端配置内容校验失败统计(按文件路径: 次数, 最后错误, 最后时间)",
 		},
 		"Time": map[string]string{
 			"Uptime":      "应用运行时间",
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fufuok/utils/xcrypto"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// 子命令退出码: 0 成功; 1 执行失败, 配置无效或对比有差异; 2 参数错误, 或对比时读取文件出错 (同 diff(1))
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type command struct {
+	name string
+	args string
+	desc string
+	run  func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"encrypt", "[-base] VALUE", "使用基础密钥加密单个值", runEncrypt},
+		{"decrypt", "[-base] VALUE", "使用基础密钥解密单个值", runDecrypt},
+		{"env-encrypt", "[-base] [-keys] FILE", "加密 .env 文件中的明文值, 原文件备份为 .bak", runEnvEncrypt},
+		{"env-reencrypt", "[-oldbase] [-newbase] FILE", "基础密钥轮换, 使用新密钥重新加密 .env 文件", runEnvReencrypt},
+		{"validate", "[-p] [-c]", "按 LoadConfig 流程加载配置并输出错误", runValidate},
+		{"render", "[-p] [-c]", "输出合并后生效的配置, 密钥脱敏", runRender},
+		{"diff", "OLD_FILE NEW_FILE", "对比两个配置文件, 有差异时退出码为 1", runDiff},
+		{"history-list", "[-p] [FILE]", "列出有历史版本的配置文件, 或指定文件的历史版本", runHistoryList},
+		{"history-show", "[-p] FILE ID", "输出配置文件指定历史版本的内容", runHistoryShow},
+		{"history-revert", "[-p] FILE ID", "将配置文件恢复为指定历史版本", runHistoryRevert},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	_, _ = fmt.Fprintf(out, "Usage:\n  %s <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(out, "  %-14s %-28s %s\n", cmd.name, cmd.args, cmd.desc)
+	}
+	_, _ = fmt.Fprintf(out, "\n不带子命令时为原加解密工具:\n")
+	flag.PrintDefaults()
+}
+
+// 子命令参数集, 含基础密钥相关的通用参数
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&baseSecretSalt, "salt", baseSecretSalt, "自定义基础密钥盐(可选)")
+	fs.StringVar(&appName, "appname", appName, "应用名, 用于解密基础密钥")
+	fs.Usage = func() {
+		cmd, _ := findCommand(name)
+		_, _ = fmt.Fprintf(fs.Output(), "Usage:\n  %s %s %s\n  %s\n\n", os.Args[0], name, cmd.args, cmd.desc)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// 解析子命令参数, 并检查位置参数个数
+func parseArgs(fs *flag.FlagSet, args []string, n int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+// 获取基础密钥原始值: 优先使用参数指定值, 否则从 BASE_SECRET_KEY 解密
+func getBaseSecret(value string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if value = xcrypto.GetenvDecrypt(config.BaseSecretEnvName, baseSecretSalt+appName); value == "" {
+		return "", fmt.Errorf("请设置 %s 加密的基础密钥环境变量, 或使用 -base 指定基础密钥", config.BaseSecretEnvName)
+	}
+	return value, nil
+}
+
+func exitErr(err error) int {
+	_, _ = fmt.Fprintln(os.Stderr, "错误:", err)
+	return exitError
+}
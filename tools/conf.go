@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// 按程序启动相同的方式加载配置: -p 程序目录, -c 主配置文件
+func loadConfig(name string, args []string) (bool, error) {
+	fs := newFlagSet(name)
+	fs.StringVar(&config.RootPath, "p", config.DefaultRootPath, "程序启动目录(bin), 配置目录为 ../etc, 环境文件目录为 ../env")
+	fs.StringVar(&config.ConfigFile, "c", config.ConfigFile, "主配置文件绝对路径(可选)")
+	fs.StringVar(&config.AppBaseSecretValue, "base", "", "基础密钥, 默认从 BASE_SECRET_KEY 解密")
+	if !parseArgs(fs, args, 0) {
+		return false, nil
+	}
+	config.AppName = appName
+	config.BaseSecretSalt = baseSecretSalt
+	// 只检查配置, 不创建目录, 不写节点信息备份, 不请求出口 IP 接口
+	config.DryRun = true
+	config.InitDefaultConfig()
+	return true, config.LoadConfig()
+}
+
+// # tools validate -p /opt/app/bin
+// 配置有效: /opt/app/etc/app.json
+func runValidate(args []string) int {
+	ok, err := loadConfig("validate", args)
+	if !ok {
+		return exitUsage
+	}
+	if err != nil {
+		printConfigErrors(err)
+		return exitError
+	}
+	fmt.Println("配置有效:", config.GetConfigFiles())
+	return exitOK
+}
+
+// 逐项输出配置错误, 展开合并的错误及其中所有的 *config.ValidationError
+func printConfigErrors(err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			printConfigErrors(e)
+		}
+		return
+	}
+	var ve *config.ValidationError
+	if errors.As(err, &ve) {
+		for _, fe := range ve.Errors {
+			_, _ = fmt.Fprintln(os.Stderr, fe)
+		}
+		return
+	}
+	exitErr(err)
+}
+
+// # tools render -p /opt/app/bin > effective.json
+func runRender(args []string) int {
+	ok, err := loadConfig("render", args)
+	if !ok {
+		return exitUsage
+	}
+	if err != nil {
+		return exitErr(err)
+	}
+	body, err := config.RenderConfig()
+	if err != nil {
+		return exitErr(err)
+	}
+	fmt.Println(string(body))
+	return exitOK
+}
+
+// # tools diff etc/app.json etc/app.new.yaml
+// log_conf.level: 2 => 1
+func runDiff(args []string) int {
+	fs := newFlagSet("diff")
+	if !parseArgs(fs, args, 2) {
+		return exitUsage
+	}
+	diff, err := config.DiffConfigFiles(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		// 区别于有差异的退出码 1
+		exitErr(err)
+		return exitUsage
+	}
+	if diff.Empty() {
+		return exitOK
+	}
+	for _, s := range diff.Strings() {
+		fmt.Println(s)
+	}
+	return exitError
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// 捕获标准错误输出
+func captureStderr(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = stderr
+	}()
+	fn()
+	_ = w.Close()
+	out, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return string(out)
+}
+
+func TestRunValidate(t *testing.T) {
+	root := t.TempDir()
+	bin, etc := filepath.Join(root, "bin"), filepath.Join(root, "etc")
+	assert.Nil(t, os.MkdirAll(bin, 0o755))
+	assert.Nil(t, os.MkdirAll(etc, 0o755))
+	nodeInfo := filepath.Join(etc, "node_info.json")
+	assert.Nil(t, os.WriteFile(nodeInfo, []byte(`{"node_ip": "10.0.0.1", "node_id": 1}`), 0o600))
+	conf := filepath.Join(etc, "app.json")
+	body := `{"node_conf": {"node_info_file": "` + nodeInfo + `", "ip_api": "http://127.0.0.1:1/ip"}}`
+	assert.Nil(t, os.WriteFile(conf, []byte(body), 0o600))
+
+	args := []string{"-p", bin, "-c", conf, "-base", "Tester"}
+	assert.Equal(t, exitOK, runValidate(args))
+
+	// 只读: 不创建日志目录, 不保存节点信息备份
+	assert.True(t, config.DryRun)
+	_, err := os.Stat(filepath.Join(root, "log"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(config.NodeInfoBackupFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// 输出所有校验错误
+	assert.Nil(t, os.WriteFile(conf, []byte(`{"log_conf": {"level": 99}, "web_conf": {"server_addr": "bad"}}`), 0o600))
+	var code int
+	out := captureStderr(t, func() {
+		code = runValidate(args)
+	})
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, "log_conf.level", out)
+	assert.Contains(t, "web_conf.server_addr", out)
+	assert.Equal(t, 2, strings.Count(strings.TrimSpace(out), "\n")+1)
+
+	assert.Equal(t, exitUsage, runValidate([]string{"-p", bin, "extra"}))
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	assert.Nil(t, os.WriteFile(a, []byte(`{"log_conf": {"level": 1}}`), 0o600))
+	assert.Nil(t, os.WriteFile(b, []byte(`{"log_conf": {"level": 2}}`), 0o600))
+
+	assert.Equal(t, exitOK, runDiff([]string{a, a}))
+	assert.Equal(t, exitError, runDiff([]string{a, b}))
+	_ = captureStderr(t, func() {
+		assert.Equal(t, exitUsage, runDiff([]string{a, filepath.Join(dir, "none.json")}))
+	})
+	assert.Equal(t, exitUsage, runDiff([]string{a}))
+}
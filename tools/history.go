@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// 历史版本子命令参数集: -p 程序目录 (历史目录为 ../etc/.history), -dir 直接指定历史目录
+func newHistoryFlagSet(name string) *flag.FlagSet {
+	fs := newFlagSet(name)
+	fs.StringVar(&config.RootPath, "p", config.DefaultRootPath, "程序启动目录(bin), 历史版本目录为 ../etc/.history")
+	fs.StringVar(&config.HistoryPath, "dir", "", "历史版本目录(可选)")
+	return fs
+}
+
+// # tools history-list -p /opt/app/bin /opt/app/etc/app.json
+// ID                             TIME                 SOURCE               SIZE  HASH
+// 20261017153045.123456-1a2b3c4d 2026-10-17 15:30:45  remote:GetHTTPSource 1024  1a2b3c4d...
+func runHistoryList(args []string) int {
+	fs := newHistoryFlagSet("history-list")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	config.InitDefaultConfig()
+
+	// 未指定文件时列出有历史版本的文件
+	if fs.NArg() == 0 {
+		files, err := config.History.Files()
+		if err != nil {
+			return exitErr(err)
+		}
+		for _, f := range files {
+			fmt.Println(f)
+		}
+		return exitOK
+	}
+
+	versions, err := config.History.List(fs.Arg(0))
+	if err != nil {
+		return exitErr(err)
+	}
+	if len(versions) == 0 {
+		return exitErr(fmt.Errorf("%w: %s", config.ErrHistoryNotFound, fs.Arg(0)))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTIME\tSOURCE\tSIZE\tHASH")
+	for _, v := range versions {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", v.ID, v.Time.Format(time.DateTime), v.Source, v.Size, v.Hash)
+	}
+	_ = w.Flush()
+	return exitOK
+}
+
+// # tools history-show -p /opt/app/bin /opt/app/etc/app.json 20261017153045.123456-1a2b3c4d
+func runHistoryShow(args []string) int {
+	fs := newHistoryFlagSet("history-show")
+	if !parseArgs(fs, args, 2) {
+		return exitUsage
+	}
+	config.InitDefaultConfig()
+	body, _, err := config.History.Show(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return exitErr(err)
+	}
+	_, _ = os.Stdout.Write(body)
+	return exitOK
+}
+
+// # tools history-revert -p /opt/app/bin /opt/app/etc/app.json 20261017153045.123456-1a2b3c4d
+// 已恢复 /opt/app/etc/app.json 为版本 20261017153045.123456-1a2b3c4d, 程序将自动热加载
+func runHistoryRevert(args []string) int {
+	fs := newHistoryFlagSet("history-revert")
+	if !parseArgs(fs, args, 2) {
+		return exitUsage
+	}
+	config.InitDefaultConfig()
+	v, err := config.History.Revert(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return exitErr(err)
+	}
+	fmt.Printf("已恢复 %s 为版本 %s, 程序将自动热加载\n", v.File, fs.Arg(1))
+	return exitOK
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"os"
+	"strings"
 
 	"github.com/fufuok/utils/base58"
 	"github.com/fufuok/utils/xcrypto"
@@ -24,9 +26,24 @@ var (
 
 	// 编码用户名密码字符串
 	user, password string
+
+	// 基础密钥轮换: 重新加密的 .env 文件, 旧/新基础密钥原始值
+	reencryptFile, oldBaseSecret, newBaseSecret string
 )
 
 func main() {
+	// 子命令: tools <command> [flags] [args]
+	if len(os.Args) > 1 {
+		if cmd, ok := findCommand(os.Args[1]); ok {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	legacyMain()
+}
+
+// 原一次性加解密工具, 无子命令时使用
+func legacyMain() {
+	flag.Usage = usage
 	flag.StringVar(&baseSecretValue, "base", "", "自定义基础密钥")
 	flag.StringVar(&baseSecretSalt, "salt", baseSecretSalt, "自定义基础密钥(可选)")
 	flag.StringVar(&appName, "appname", appName, "应用名, 用于加密基础密钥")
@@ -36,6 +53,10 @@ func main() {
 	flag.StringVar(&user, "user", "", "用户名")
 	flag.StringVar(&password, "password", "", "密码")
 
+	flag.StringVar(&reencryptFile, "reencrypt", "", "使用新基础密钥重新加密的 .env 文件")
+	flag.StringVar(&oldBaseSecret, "oldbase", "", "旧基础密钥, 默认从 BASE_SECRET_KEY_SECONDARY 解密")
+	flag.StringVar(&newBaseSecret, "newbase", "", "新基础密钥, 默认从 BASE_SECRET_KEY 解密")
+
 	flag.StringVar(&key, "key", "envname", "环境变量名")
 	flag.StringVar(&data, "data", "", "待加密字符串")
 	flag.Parse()
@@ -82,6 +103,33 @@ func main() {
 		return
 	}
 
+	// # export BASE_SECRET_KEY=新基础密钥加密串
+	// # export BASE_SECRET_KEY_SECONDARY=TQeKrAAFJ5godyTxtDw2o1
+	// # go run main.go -reencrypt=/opt/app/env/app.env -appname="FF.YourAPP"
+	// 或直接指定旧/新基础密钥原始值:
+	// # go run main.go -reencrypt=/opt/app/env/app.env -oldbase="FF~~666" -newbase="FF~~777"
+	//
+	// 已重新加密 (原文件备份为 /opt/app/env/app.env.bak):
+	//        REDIS_AUTH
+	if reencryptFile != "" {
+		if oldBaseSecret == "" {
+			oldBaseSecret = xcrypto.GetenvDecrypt(config.BaseSecretSecondaryEnvName, baseSecretSalt+appName)
+		}
+		if newBaseSecret == "" {
+			newBaseSecret = xcrypto.GetenvDecrypt(config.BaseSecretEnvName, baseSecretSalt+appName)
+		}
+		keys, err := config.ReencryptEnvFile(reencryptFile, oldBaseSecret, newBaseSecret)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(keys) == 0 {
+			fmt.Print("\n没有需要重新加密的变量\n\n")
+			return
+		}
+		fmt.Printf("\n已重新加密 (原文件备份为 %s.bak):\n\t%s\n\n", reencryptFile, strings.Join(keys, "\n\t"))
+		return
+	}
+
 	// # export BASE_SECRET_KEY=TQeKrAAFJ5godyTxtDw2o1
 	// # go run main.go -key="REDIS_AUTH" -data="redis12345" -appname="FF.YourAPP"
 	// APP_NAME: FF.YourAPP 基础密钥: FF~~666
@@ -110,8 +158,8 @@ func main() {
 	fmt.Println("APP_NAME:", appName, "基础密钥:", baseSecretValue)
 	if data != "" {
 		// 使用基础密钥加密
-		result, err := xcrypto.SetenvEncrypt(key, data, baseSecretValue)
-		if err != nil {
+		result := config.EncryptSecret(data, baseSecretValue)
+		if err := os.Setenv(key, result); err != nil {
 			log.Fatalln(err)
 		}
 		fmt.Printf("\nplaintext:\n\t%s\nciphertext:\n\t%s\nLinux:\n\texport %s=%s\nWindows:\n\tset %s=%s\n\n",
@@ -125,10 +173,10 @@ func main() {
 	//
 	// testGetenv: REDIS_AUTH = redis12345
 	//
-	result := xcrypto.GetenvDecrypt(key, baseSecretValue)
+	result, _ := config.DecryptSecretWith(os.Getenv(key), baseSecretValue)
 	fmt.Printf("\ntestGetenv: %s = %s\n\n", key, result)
 
 	// 程序中要使用上面示例中的 REDIS_AUTH 一般是:
-	// redisAuth := xcrypto.GetenvDecrypt("REDIS_AUTH", config.Config().SYSConf.BaseSecretValue)
+	// redisAuth := config.GetenvDecrypt("REDIS_AUTH")
 	// fmt.Println(redisAuth) // redis12345
 }
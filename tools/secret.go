@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fufuok/utils/xcrypto"
+
+	"github.com/fufuok/pkg/config"
+)
+
+// # tools encrypt -base="FF~~666" redis12345
+// XYq6HwQGzuiQmk2rMEsoE2
+func runEncrypt(args []string) int {
+	fs := newFlagSet("encrypt")
+	base := fs.String("base", "", "基础密钥, 默认从 BASE_SECRET_KEY 解密")
+	if !parseArgs(fs, args, 1) {
+		return exitUsage
+	}
+	secret, err := getBaseSecret(*base)
+	if err != nil {
+		return exitErr(err)
+	}
+	fmt.Println(config.EncryptSecret(fs.Arg(0), secret))
+	return exitOK
+}
+
+// # tools decrypt -base="FF~~666" XYq6HwQGzuiQmk2rMEsoE2
+// redis12345
+func runDecrypt(args []string) int {
+	fs := newFlagSet("decrypt")
+	base := fs.String("base", "", "基础密钥, 默认从 BASE_SECRET_KEY 解密")
+	if !parseArgs(fs, args, 1) {
+		return exitUsage
+	}
+	secret, err := getBaseSecret(*base)
+	if err != nil {
+		return exitErr(err)
+	}
+	value, ok := config.DecryptSecretWith(fs.Arg(0), secret)
+	if !ok || value == "" {
+		return exitErr(fmt.Errorf("解密失败"))
+	}
+	fmt.Println(value)
+	return exitOK
+}
+
+// # tools env-encrypt -keys=REDIS_AUTH,API_KEY /opt/app/env/app.env
+// REDIS_AUTH
+// API_KEY
+func runEnvEncrypt(args []string) int {
+	fs := newFlagSet("env-encrypt")
+	base := fs.String("base", "", "基础密钥, 默认从 BASE_SECRET_KEY 解密")
+	keys := fs.String("keys", "", "只加密指定变量, 英文逗号分隔, 默认所有明文值")
+	if !parseArgs(fs, args, 1) {
+		return exitUsage
+	}
+	secret, err := getBaseSecret(*base)
+	if err != nil {
+		return exitErr(err)
+	}
+	var only []string
+	for _, k := range strings.Split(*keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			only = append(only, k)
+		}
+	}
+	encrypted, err := config.EncryptEnvFile(fs.Arg(0), secret, only...)
+	if err != nil {
+		return exitErr(err)
+	}
+	printKeys(encrypted)
+	return exitOK
+}
+
+// # tools env-reencrypt -oldbase="FF~~666" -newbase="FF~~777" /opt/app/env/app.env
+// REDIS_AUTH
+func runEnvReencrypt(args []string) int {
+	fs := newFlagSet("env-reencrypt")
+	oldBase := fs.String("oldbase", "", "旧基础密钥, 默认从 BASE_SECRET_KEY_SECONDARY 解密")
+	newBase := fs.String("newbase", "", "新基础密钥, 默认从 BASE_SECRET_KEY 解密")
+	if !parseArgs(fs, args, 1) {
+		return exitUsage
+	}
+	if *oldBase == "" {
+		*oldBase = xcrypto.GetenvDecrypt(config.BaseSecretSecondaryEnvName, baseSecretSalt+appName)
+	}
+	secret, err := getBaseSecret(*newBase)
+	if err != nil {
+		return exitErr(err)
+	}
+	keys, err := config.ReencryptEnvFile(fs.Arg(0), *oldBase, secret)
+	if err != nil {
+		return exitErr(err)
+	}
+	printKeys(keys)
+	return exitOK
+}
+
+// 输出已处理的变量名, 无变化时提示到标准错误
+func printKeys(keys []string) {
+	if len(keys) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "没有需要处理的变量")
+		return
+	}
+	fmt.Println(strings.Join(keys, "\n"))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/config"
+)
+
+func TestRunEnvReencrypt(t *testing.T) {
+	oldKey, newKey := "old~~666", "new~~777"
+	t.Setenv(config.BaseSecretKeyNameEnvName, "REAL_BASE_KEY")
+	realBase := config.EncryptSecret("base", oldKey)
+	f := filepath.Join(t.TempDir(), "app.env")
+	body := "REDIS_AUTH=" + config.EncryptSecret("redis12345", oldKey) + "\n" +
+		"REAL_BASE_KEY=" + realBase + "\n"
+	assert.Nil(t, os.WriteFile(f, []byte(body), 0o600))
+
+	assert.Equal(t, exitOK, runEnvReencrypt([]string{"-oldbase", oldKey, "-newbase", newKey, f}))
+	got, err := os.ReadFile(f)
+	assert.Nil(t, err)
+	// 自定义名称的基础密钥变量不处理
+	assert.Equal(t, "REDIS_AUTH="+config.EncryptSecret("redis12345", newKey)+"\nREAL_BASE_KEY="+realBase+"\n", string(got))
+}
+
+func TestRunEncryptDecrypt(t *testing.T) {
+	secret := "base~~666"
+	enc := config.EncryptSecret("redis12345", secret)
+	v, ok := config.DecryptSecretWith(enc, secret)
+	assert.True(t, ok)
+	assert.Equal(t, "redis12345", v)
+
+	assert.Equal(t, exitOK, runDecrypt([]string{"-base", secret, enc}))
+	_ = captureStderr(t, func() {
+		assert.Equal(t, exitError, runDecrypt([]string{"-base", "other", enc}))
+	})
+	assert.Equal(t, exitUsage, runEncrypt([]string{"-base", secret}))
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/fufuok/pkg/admin"
+	"github.com/fufuok/pkg/web/fiber/middleware"
+	"github.com/fufuok/pkg/web/fiber/response"
+)
+
+// AdminGroup 管理接口的 Web 服务分组, 与业务分组一起传给 RunGroups
+// 监听地址由配置 web_conf.groups.admin 指定, 未配置时不监听
+func AdminGroup() ServerGroup {
+	return ServerGroup{
+		Name: admin.GroupName,
+		Setup: func(app *fiber.App) *fiber.App {
+			SetupAdminRouter(app)
+			return app
+		},
+	}
+}
+
+// SetupAdminRouter 设置管理接口路由 (/admin), 需同时通过白名单和签名检查 (middleware.SignChecker)
+//
+// POST /reload               重新加载配置
+// POST /fetch                立即获取远端配置
+// GET  /jobs                 定时任务列表
+// POST /jobs/:name/stop      暂停定时任务
+// POST /jobs/:name/start     恢复暂停的定时任务
+// GET  /log/level            当前日志级别
+// POST /log/level/:level     调整日志级别, 可选 ?module=a,b 仅调整指定模块
+// POST /log/debug/:minutes    临时开启 Debug 日志, 到期自动恢复, 可选 ?module=a,b
+// GET  /alarm                报警开关状态
+// POST /alarm/on, /alarm/off 开启或关闭报警
+// GET  /stats[/:name]        统计数据, 见 admin.RegisterStats
+// GET  /config/history                 有历史版本的文件列表, 指定 ?file= 时为该文件的历史版本
+// GET  /config/history/show?file=&id=  历史版本内容, env 文件的变量值脱敏
+// POST /config/history/revert?file=&id= 恢复为指定历史版本
+func SetupAdminRouter(r fiber.Router) {
+	g := r.Group("/admin", middleware.CheckWhitelistAnd(middleware.SignChecker, true))
+	g.Post("/reload", func(c fiber.Ctx) error {
+		return response.APISuccess(c, fiber.Map{"queued": admin.Reload()}, 1)
+	})
+	g.Post("/fetch", func(c fiber.Ctx) error {
+		return response.APISuccess(c, fiber.Map{"fetchers": admin.FetchNow()}, 1)
+	})
+	g.Get("/jobs", func(c fiber.Ctx) error {
+		jobs := admin.Jobs()
+		return response.APISuccess(c, jobs, len(jobs))
+	})
+	g.Post("/jobs/:name/stop", func(c fiber.Ctx) error {
+		return adminResponse(c, admin.StopJob(c.Params("name")))
+	})
+	g.Post("/jobs/:name/start", func(c fiber.Ctx) error {
+		return adminResponse(c, admin.StartJob(c.Params("name")))
+	})
+	g.Get("/log/level", func(c fiber.Ctx) error {
+		return response.APISuccess(c, admin.LogLevel(), 1)
+	})
+	g.Post("/log/level/:level", func(c fiber.Ctx) error {
+		return adminResponse(c, admin.SetLogLevel(c.Params("level"), adminModules(c.Query("module"))...))
+	})
+	g.Post("/log/debug/:minutes", func(c fiber.Ctx) error {
+		minutes, err := strconv.Atoi(c.Params("minutes"))
+		if err != nil {
+			return response.APIFailure(c, "invalid minutes", nil)
+		}
+		return adminResponse(c, admin.DebugFor(time.Duration(minutes)*time.Minute, adminModules(c.Query("module"))...))
+	})
+	g.Get("/alarm", func(c fiber.Ctx) error {
+		return response.APISuccess(c, fiber.Map{"alarm_on": admin.AlarmOn()}, 1)
+	})
+	g.Post("/alarm/on", func(c fiber.Ctx) error {
+		admin.SetAlarm(true)
+		return response.APISuccessNil(c)
+	})
+	g.Post("/alarm/off", func(c fiber.Ctx) error {
+		admin.SetAlarm(false)
+		return response.APISuccessNil(c)
+	})
+	g.Get("/stats", func(c fiber.Ctx) error {
+		return adminStats(c)
+	})
+	g.Get("/stats/:name", func(c fiber.Ctx) error {
+		return adminStats(c, c.Params("name"))
+	})
+	setupHistoryRouter(g)
+}
+
+func adminStats(c fiber.Ctx, names ...string) error {
+	data, err := admin.Stats(names...)
+	if err != nil {
+		return adminResponse(c, err)
+	}
+	return response.APISuccess(c, data, len(data))
+}
+
+func adminResponse(c fiber.Ctx, err error) error {
+	switch {
+	case err == nil:
+		return response.APISuccessNil(c)
+	case errors.Is(err, admin.ErrJobNotFound), errors.Is(err, admin.ErrUnknownStats):
+		return response.APIException(c, fiber.StatusNotFound, err.Error(), nil)
+	default:
+		return response.APIFailure(c, err.Error(), nil)
+	}
+}
+
+// 逗号分隔的模块名列表
+func adminModules(s string) []string {
+	var modules []string
+	for name := range strings.SplitSeq(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			modules = append(modules, name)
+		}
+	}
+	return modules
+}
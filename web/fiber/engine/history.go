@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/web/fiber/response"
+)
+
+// 配置文件历史版本管理路由 (config.History), 仅由 SetupAdminRouter 挂载到需白名单和签名检查的 /admin 路由组
+//
+// GET  /config/history                 有历史版本的文件列表, 指定 ?file= 时为该文件的历史版本
+// GET  /config/history/show?file=&id=  历史版本内容, env 文件的变量值脱敏
+// POST /config/history/revert?file=&id= 恢复为指定历史版本
+func setupHistoryRouter(r fiber.Router) {
+	r.Get("/config/history", func(c fiber.Ctx) error {
+		file := c.Query("file")
+		if file == "" {
+			files, err := config.History.Files()
+			if err != nil {
+				return response.APIException(c, fiber.StatusInternalServerError, err.Error(), nil)
+			}
+			return response.APISuccess(c, files, len(files))
+		}
+		versions, err := config.History.List(file)
+		if err != nil {
+			return response.APIException(c, fiber.StatusInternalServerError, err.Error(), nil)
+		}
+		return response.APISuccess(c, versions, len(versions))
+	})
+	r.Get("/config/history/show", func(c fiber.Ctx) error {
+		body, v, err := config.History.Show(c.Query("file"), c.Query("id"))
+		if err != nil {
+			return historyException(c, err)
+		}
+		return response.APISuccess(c, fiber.Map{"version": v, "content": string(config.RedactHistoryContent(v.File, body))}, 1)
+	})
+	r.Post("/config/history/revert", func(c fiber.Ctx) error {
+		v, err := config.History.Revert(c.Query("file"), c.Query("id"))
+		if err != nil {
+			return historyException(c, err)
+		}
+		return response.APISuccess(c, v, 1)
+	})
+}
+
+func historyException(c fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	if errors.Is(err, config.ErrHistoryNotFound) {
+		code = fiber.StatusNotFound
+	}
+	return response.APIException(c, code, err.Error(), nil)
+}
@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"log"
 	"strings"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/gofiber/fiber/v3/middleware/compress"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/fufuok/pkg/common"
 	"github.com/fufuok/pkg/config"
 	"github.com/fufuok/pkg/json"
 	"github.com/fufuok/pkg/logger"
@@ -111,23 +114,35 @@ func newApp(setup App, cfg config.WebConf) *fiber.App {
 
 func runOne(setup App, cfg config.WebConf) error {
 	app := newApp(setup, cfg)
+	// 平滑重启时停止接受新连接, 等待已有请求处理完成
+	common.RegisterShutdown(app.ShutdownWithContext)
 
 	eg := errgroup.Group{}
 	if cfg.ServerHttpsAddr != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("tls: cannot load TLS key pair from certFile=%q and keyFile=%q: %w",
+				cfg.CertFile, cfg.KeyFile, err)
+		}
+		tlsConfig := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
 		for rawAddr := range strings.SplitSeq(cfg.ServerHttpsAddr, ",") {
 			// trim 并跳过空地址, 避免空串或前后空格导致监听失败
 			addr := strings.TrimSpace(rawAddr)
 			if addr == "" {
 				continue
 			}
-			eg.Go(func() (err error) {
+			eg.Go(func() error {
 				logger.Warn().Str("addr", addr).Str("service", cfg.Name).Msg("HTTPS server started")
-				err = app.Listen(addr, fiber.ListenConfig{
+				ln, err := common.Listen(addr)
+				if err != nil {
+					return err
+				}
+				return app.Listener(tls.NewListener(ln, tlsConfig), fiber.ListenConfig{
 					DisableStartupMessage: true,
-					CertFile:              cfg.CertFile,
-					CertKeyFile:           cfg.KeyFile,
 				})
-				return
 			})
 		}
 	}
@@ -137,12 +152,15 @@ func runOne(setup App, cfg config.WebConf) error {
 		if addr == "" {
 			continue
 		}
-		eg.Go(func() (err error) {
+		eg.Go(func() error {
 			logger.Warn().Str("addr", addr).Str("service", cfg.Name).Msg("HTTP server started")
-			err = app.Listen(addr, fiber.ListenConfig{
+			ln, err := common.Listen(addr)
+			if err != nil {
+				return err
+			}
+			return app.Listener(ln, fiber.ListenConfig{
 				DisableStartupMessage: true,
 			})
-			return
 		})
 	}
 	if err := eg.Wait(); err != nil {
@@ -2,6 +2,10 @@ package engine
 
 import (
 	"github.com/gofiber/fiber/v3"
+
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/health"
+	"github.com/fufuok/pkg/web/fiber/middleware"
 )
 
 // SetupSYSRouter 设置系统信息路由
@@ -14,6 +18,30 @@ func SetupSYSRouter(app *fiber.App) {
 	})
 }
 
+// SetupHealthRouter 设置健康检查路由, 检查失败时状态码为 503
+// GET /healthz 存活检查, GET /readyz 就绪检查, 检查项见 health.Register
+// 需自行挂载, 路由不鉴权, 仅配置了白名单且客户端在白名单中时返回检查项的错误详情
+func SetupHealthRouter(r fiber.Router) {
+	r.Get("/healthz", func(c fiber.Ctx) error {
+		return healthResponse(c, health.Live(c.Context()))
+	})
+	r.Get("/readyz", func(c fiber.Ctx) error {
+		return healthResponse(c, health.Ready(c.Context()))
+	})
+}
+
+func healthResponse(c fiber.Ctx, report health.Report) error {
+	code := fiber.StatusOK
+	if report.Status != health.StatusOK {
+		code = fiber.StatusServiceUnavailable
+	}
+	// 未配置白名单时所有客户端均不返回错误详情
+	if len(config.Whitelist) == 0 || !middleware.WhitelistChecker(c) {
+		report = report.Redacted()
+	}
+	return c.Status(code).JSON(report)
+}
+
 // SetupExceptionRouter 设置异常请求路由.
 //
 // Fiber 没有与 Gin NoRoute/NoMethod 完全一致的入口, 这里必须在全部业务路由注册完成后最后调用.
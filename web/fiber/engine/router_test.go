@@ -1,14 +1,24 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v3"
 
 	"github.com/fufuok/utils/assert"
+
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/health"
 )
 
 // TestSetupExceptionRouterKeepsRegisteredRoutes 验证异常路由按约定最后注册时,
@@ -108,3 +118,85 @@ func assertFiberResponse(t *testing.T, app *fiber.App, method, path string, stat
 	assert.Nil(t, err)
 	assert.Equal(t, body, string(actual))
 }
+
+func TestHistoryRouter(t *testing.T) {
+	dir := t.TempDir()
+	old := config.History
+	config.History = &config.HistoryStore{Dir: filepath.Join(dir, ".history"), Limit: 5}
+	defer func() { config.History = old }()
+
+	file := filepath.Join(dir, "app.json")
+	assert.Nil(t, os.WriteFile(file, []byte(`{"v":1}`), 0o600))
+	v1, _ := config.History.Record(file, config.HistorySourceLocal)
+	assert.Nil(t, os.WriteFile(file, []byte(`{"v":2}`), 0o600))
+	_, _ = config.History.Record(file, config.HistorySourceLocal)
+
+	app := fiber.New()
+	setupHistoryRouter(app.Group("/admin"))
+
+	q := "?file=" + url.QueryEscape(file) + "&id=" + url.QueryEscape(v1.ID)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/config/history?file="+url.QueryEscape(file), nil))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, `"count":2`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/admin/config/history/revert"+q, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	b, _ := os.ReadFile(file)
+	assert.Equal(t, `{"v":1}`, string(b))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/admin/config/history/show?file=x&id=y", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSetupHealthRouter(t *testing.T) {
+	health.Register("test_ready", health.Readiness, func(context.Context) error {
+		return errors.New("warming up")
+	})
+	defer health.Unregister("test_ready")
+
+	app := fiber.New()
+	SetupHealthRouter(app)
+
+	// 未配置白名单时仅返回整体状态
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, `"status":"fail"`, string(body))
+	assert.False(t, strings.Contains(string(body), "warming up"))
+
+	whitelist := config.Whitelist
+	defer func() {
+		config.Whitelist = whitelist
+	}()
+	_, all, _ := net.ParseCIDR("0.0.0.0/0")
+	config.Whitelist = map[*net.IPNet]int64{all: 0}
+
+	// 测试环境未加载主配置, config 检查失败
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, `"error":"config not loaded"`, string(body))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, `"error":"warming up"`, string(body))
+	assert.Contains(t, `"latency":`, string(body))
+
+	// 非白名单客户端不返回错误详情
+	_, other, _ := net.ParseCIDR("10.255.255.1/32")
+	config.Whitelist = map[*net.IPNet]int64{other: 0}
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.False(t, strings.Contains(string(body), "warming up"))
+	assert.Contains(t, `"status":"fail"`, string(body))
+}
@@ -73,6 +73,36 @@ func CounterStats() map[string]any {
 	return stats
 }
 
+// CounterTotals 所有路由的请求总数和错误数
+func CounterTotals() (in, errs uint64) {
+	for _, v := range httpCounter {
+		in += v.In.Load()
+		errs += v.Err.Load()
+	}
+	return
+}
+
+// ErrorRateProbe 请求错误率检查, 可用作灰度发布健康检查: master.RegisterRolloutProbe("http", ErrorRateProbe(0.05, 100))
+// 统计创建后的请求, 请求数达到 minRequests 且错误率超过 maxRate 时返回错误
+func ErrorRateProbe(maxRate float64, minRequests uint64) func() error {
+	in0, errs0 := CounterTotals()
+	return func() error {
+		in, errs := CounterTotals()
+		// 统计数据已被重置
+		if in < in0 || errs < errs0 {
+			in0, errs0 = 0, 0
+		}
+		in, errs = in-in0, errs-errs0
+		if in == 0 || in < minRequests {
+			return nil
+		}
+		if rate := float64(errs) / float64(in); rate > maxRate {
+			return fmt.Errorf("http error rate %.2f%% > %.2f%% (%d/%d)", rate*100, maxRate*100, errs, in)
+		}
+		return nil
+	}
+}
+
 // ResetStatistics 重置统计数据
 func ResetStatistics() {
 	for _, counter := range httpCounter {
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fufuok/pkg/admin"
+	"github.com/fufuok/pkg/web/gin/middleware"
+	"github.com/fufuok/pkg/web/gin/response"
+)
+
+// AdminGroup 管理接口的 Web 服务分组, 与业务分组一起传给 RunGroups
+// 监听地址由配置 web_conf.groups.admin 指定, 未配置时不监听
+func AdminGroup() ServerGroup {
+	return ServerGroup{
+		Name: admin.GroupName,
+		Setup: func(app *gin.Engine) *gin.Engine {
+			SetupAdminRouter(app)
+			return app
+		},
+	}
+}
+
+// SetupAdminRouter 设置管理接口路由 (/admin), 需同时通过白名单和签名检查 (middleware.SignChecker)
+//
+// POST /reload               重新加载配置
+// POST /fetch                立即获取远端配置
+// GET  /jobs                 定时任务列表
+// POST /jobs/:name/stop      暂停定时任务
+// POST /jobs/:name/start     恢复暂停的定时任务
+// GET  /log/level            当前日志级别
+// POST /log/level/:level     调整日志级别, 可选 ?module=a,b 仅调整指定模块
+// POST /log/debug/:minutes    临时开启 Debug 日志, 到期自动恢复, 可选 ?module=a,b
+// GET  /alarm                报警开关状态
+// POST /alarm/on, /alarm/off 开启或关闭报警
+// GET  /stats[/:name]        统计数据, 见 admin.RegisterStats
+// GET  /config/history                 有历史版本的文件列表, 指定 ?file= 时为该文件的历史版本
+// GET  /config/history/show?file=&id=  历史版本内容, env 文件的变量值脱敏
+// POST /config/history/revert?file=&id= 恢复为指定历史版本
+func SetupAdminRouter(r gin.IRouter) {
+	g := r.Group("/admin", middleware.CheckWhitelistAnd(middleware.SignChecker, true))
+	g.POST("/reload", func(c *gin.Context) {
+		response.APISuccess(c, gin.H{"queued": admin.Reload()}, 1)
+	})
+	g.POST("/fetch", func(c *gin.Context) {
+		response.APISuccess(c, gin.H{"fetchers": admin.FetchNow()}, 1)
+	})
+	g.GET("/jobs", func(c *gin.Context) {
+		jobs := admin.Jobs()
+		response.APISuccess(c, jobs, len(jobs))
+	})
+	g.POST("/jobs/:name/stop", func(c *gin.Context) {
+		adminResponse(c, admin.StopJob(c.Param("name")))
+	})
+	g.POST("/jobs/:name/start", func(c *gin.Context) {
+		adminResponse(c, admin.StartJob(c.Param("name")))
+	})
+	g.GET("/log/level", func(c *gin.Context) {
+		response.APISuccess(c, admin.LogLevel(), 1)
+	})
+	g.POST("/log/level/:level", func(c *gin.Context) {
+		adminResponse(c, admin.SetLogLevel(c.Param("level"), adminModules(c.Query("module"))...))
+	})
+	g.POST("/log/debug/:minutes", func(c *gin.Context) {
+		minutes, err := strconv.Atoi(c.Param("minutes"))
+		if err != nil {
+			response.APIFailure(c, "invalid minutes", nil)
+			return
+		}
+		adminResponse(c, admin.DebugFor(time.Duration(minutes)*time.Minute, adminModules(c.Query("module"))...))
+	})
+	g.GET("/alarm", func(c *gin.Context) {
+		response.APISuccess(c, gin.H{"alarm_on": admin.AlarmOn()}, 1)
+	})
+	g.POST("/alarm/on", func(c *gin.Context) {
+		admin.SetAlarm(true)
+		response.APISuccessNil(c)
+	})
+	g.POST("/alarm/off", func(c *gin.Context) {
+		admin.SetAlarm(false)
+		response.APISuccessNil(c)
+	})
+	g.GET("/stats", func(c *gin.Context) {
+		adminStats(c)
+	})
+	g.GET("/stats/:name", func(c *gin.Context) {
+		adminStats(c, c.Param("name"))
+	})
+	setupHistoryRouter(g)
+}
+
+func adminStats(c *gin.Context, names ...string) {
+	data, err := admin.Stats(names...)
+	if err != nil {
+		adminResponse(c, err)
+		return
+	}
+	response.APISuccess(c, data, len(data))
+}
+
+func adminResponse(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		response.APISuccessNil(c)
+	case errors.Is(err, admin.ErrJobNotFound), errors.Is(err, admin.ErrUnknownStats):
+		response.APIException(c, http.StatusNotFound, err.Error(), nil)
+	default:
+		response.APIFailure(c, err.Error(), nil)
+	}
+}
+
+// 逗号分隔的模块名列表
+func adminModules(s string) []string {
+	var modules []string
+	for name := range strings.SplitSeq(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			modules = append(modules, name)
+		}
+	}
+	return modules
+}
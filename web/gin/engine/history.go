@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/web/gin/response"
+)
+
+// 配置文件历史版本管理路由 (config.History), 仅由 SetupAdminRouter 挂载到需白名单和签名检查的 /admin 路由组
+//
+// GET  /config/history                 有历史版本的文件列表, 指定 ?file= 时为该文件的历史版本
+// GET  /config/history/show?file=&id=  历史版本内容, env 文件的变量值脱敏
+// POST /config/history/revert?file=&id= 恢复为指定历史版本
+func setupHistoryRouter(r gin.IRouter) {
+	r.GET("/config/history", func(c *gin.Context) {
+		file := c.Query("file")
+		if file == "" {
+			files, err := config.History.Files()
+			if err != nil {
+				response.APIException(c, http.StatusInternalServerError, err.Error(), nil)
+				return
+			}
+			response.APISuccess(c, files, len(files))
+			return
+		}
+		versions, err := config.History.List(file)
+		if err != nil {
+			response.APIException(c, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+		response.APISuccess(c, versions, len(versions))
+	})
+	r.GET("/config/history/show", func(c *gin.Context) {
+		body, v, err := config.History.Show(c.Query("file"), c.Query("id"))
+		if err != nil {
+			historyException(c, err)
+			return
+		}
+		response.APISuccess(c, gin.H{"version": v, "content": string(config.RedactHistoryContent(v.File, body))}, 1)
+	})
+	r.POST("/config/history/revert", func(c *gin.Context) {
+		v, err := config.History.Revert(c.Query("file"), c.Query("id"))
+		if err != nil {
+			historyException(c, err)
+			return
+		}
+		response.APISuccess(c, v, 1)
+	})
+}
+
+func historyException(c *gin.Context, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, config.ErrHistoryNotFound) {
+		code = http.StatusNotFound
+	}
+	response.APIException(c, code, err.Error(), nil)
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/fufuok/pkg/common"
 	"github.com/fufuok/pkg/config"
 	"github.com/fufuok/pkg/logger"
 	"github.com/fufuok/pkg/web/gin/middleware"
@@ -143,7 +145,12 @@ func runOne(setup App, cfg config.WebConf, groupName ...string) error {
 					ErrorLog:          log.New(io.Discard, "", 0), // 禁用底层服务器错误日志 (TLS 握手/连接重置等)
 					ReadHeaderTimeout: 5 * time.Second,            // 防止 Slowloris 攻击
 				}
-				return server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+				ln, err := common.Listen(addr)
+				if err != nil {
+					return err
+				}
+				common.RegisterShutdown(server.Shutdown)
+				return ignoreServerClosed(server.ServeTLS(ln, cfg.CertFile, cfg.KeyFile))
 			})
 		}
 	}
@@ -161,7 +168,12 @@ func runOne(setup App, cfg config.WebConf, groupName ...string) error {
 				ErrorLog:          log.New(io.Discard, "", 0), // 禁用底层服务器错误日志 (TLS 握手/连接重置等)
 				ReadHeaderTimeout: 5 * time.Second,            // 防止 Slowloris 攻击
 			}
-			return server.ListenAndServe()
+			ln, err := common.Listen(addr)
+			if err != nil {
+				return err
+			}
+			common.RegisterShutdown(server.Shutdown)
+			return ignoreServerClosed(server.Serve(ln))
 		})
 	}
 	if err := eg.Wait(); err != nil {
@@ -170,6 +182,14 @@ func runOne(setup App, cfg config.WebConf, groupName ...string) error {
 	return nil
 }
 
+// 平滑重启时服务被关闭 (common.Shutdown), 不作为错误
+func ignoreServerClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
 // SetTrustedProxies 使用当前全局 web_conf 更新所有已启动 gin.Engine 的代理信任配置.
 // 多组监听时每组都有独立 Engine, Runtime reload 需要逐个刷新.
 //
@@ -4,6 +4,10 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/fufuok/pkg/config"
+	"github.com/fufuok/pkg/health"
+	"github.com/fufuok/pkg/web/gin/middleware"
 )
 
 // SetupSYSRouter 设置系统信息路由
@@ -16,6 +20,30 @@ func SetupSYSRouter(app *gin.Engine) {
 	})
 }
 
+// SetupHealthRouter 设置健康检查路由, 检查失败时状态码为 503
+// GET /healthz 存活检查, GET /readyz 就绪检查, 检查项见 health.Register
+// 需自行挂载, 路由不鉴权, 仅配置了白名单且客户端在白名单中时返回检查项的错误详情
+func SetupHealthRouter(r gin.IRouter) {
+	r.GET("/healthz", func(c *gin.Context) {
+		healthResponse(c, health.Live(c.Request.Context()))
+	})
+	r.GET("/readyz", func(c *gin.Context) {
+		healthResponse(c, health.Ready(c.Request.Context()))
+	})
+}
+
+func healthResponse(c *gin.Context, report health.Report) {
+	code := http.StatusOK
+	if report.Status != health.StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	// 未配置白名单时所有客户端均不返回错误详情
+	if len(config.Whitelist) == 0 || !middleware.WhitelistChecker(c) {
+		report = report.Redacted()
+	}
+	c.JSON(code, report)
+}
+
 // SetupExceptionRouter 设置异常请求路由
 func SetupExceptionRouter(app *gin.Engine) {
 	app.NoRoute(func(c *gin.Context) {
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fufuok/pkg/common"
+	"github.com/fufuok/pkg/config"
+)
+
+// SignHeaderName 请求签名的请求头名称, 不从查询参数获取, 避免签名出现在访问日志中
+var SignHeaderName = "X-Sign"
+
+// SignChecker 是否通过了接口签名检查 (common.GenRequestSignNow, 密钥: WEB_SIGN_KEY), 未配置密钥时不通过
+// 签名绑定请求方法, 路径, 查询参数和请求体, 如: common.GenRequestSignNow(key, "POST", "/admin/reload", "", nil)
+// 可配合白名单检查使用: CheckWhitelistAnd(SignChecker, true)
+func SignChecker(c *gin.Context) bool {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			return false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	cfg := config.Config().WebConf
+	return common.VerifyRequestSignTTL(cfg.SignKey, c.GetHeader(SignHeaderName),
+		c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body, cfg.SignTTL)
+}